@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"coral_cli/internal/logging"
+	"coral_cli/pkg/coral"
+)
+
+var (
+	addComposePath      string
+	addEnvFile          string
+	addName             string
+	addHandle           string
+	addReloadSignal     string
+	addHealthTimeout    float32
+	addSkipVersionCheck bool
+)
+
+func init() {
+	addCmd.Args = cobra.NoArgs
+
+	addCmd.Flags().StringVarP(&addComposePath, "compose-file", "f", "", "Path to Docker Compose .yaml file with the drivers and skillsets to add")
+	addCmd.Flags().StringVar(&addEnvFile, "env-file", "", "Optional path to .env file to use for compose substitutions")
+	addCmd.Flags().StringVarP(&addName, "name", "n", "", "Name of the running instance to add the services to")
+	addCmd.Flags().StringVar(&addHandle, "handle", "", "Handle of the running instance to add the services to")
+	addCmd.Flags().StringVar(&addReloadSignal, "reload-signal", "", "Signal sent to each running executor once its libraries are re-injected (e.g. SIGHUP), so it can reload plugins")
+	addCmd.Flags().Float32Var(&addHealthTimeout, "health-timeout", 120.0, "Seconds to wait for the added services to become healthy before re-injecting executors")
+	addCmd.Flags().BoolVar(&addSkipVersionCheck, "skip-version-check", false, "Skip coral.version compatibility check between CLI and images")
+
+	addCmd.RegisterFlagCompletionFunc("compose-file", completeComposeFiles)
+	addCmd.RegisterFlagCompletionFunc("name", completeInstanceNames)
+	addCmd.RegisterFlagCompletionFunc("handle", completeInstanceHandles)
+}
+
+var addCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Starts additional drivers and skillsets in a running Coral instance and re-injects its executors",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return add(cmd.Context(), coral.AddOptions{
+			ComposePath:      addComposePath,
+			EnvFile:          addEnvFile,
+			HealthTimeout:    time.Duration(addHealthTimeout * float32(time.Second)),
+			ReloadSignal:     addReloadSignal,
+			SkipVersionCheck: addSkipVersionCheck,
+		})
+	},
+}
+
+func add(ctx context.Context, opts coral.AddOptions) error {
+	if (addName == "") == (addHandle == "") {
+		return &coral.Error{Code: coral.ErrCodeInvalidInput, Err: fmt.Errorf("exactly one of --name or --handle is required")}
+	}
+	inst, err := openByNameOrHandle(ctx, addName, addHandle)
+	if err != nil {
+		return err
+	}
+	if inst == nil {
+		return &coral.Error{Code: coral.ErrCodeNotFound, Err: fmt.Errorf("no instance found with handle %s", addHandle)}
+	}
+
+	result, err := coral.NewLauncher(Version).Add(ctx, inst, opts)
+	if err != nil {
+		return err
+	}
+	logging.Successf("Added %d service(s) to %s and re-injected %d executor(s)", countContainers(result.Added), describeInstance(inst), len(result.Injected))
+	return printResult(result)
+}
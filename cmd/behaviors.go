@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"coral_cli/pkg/coral"
+)
+
+var (
+	behaviorsComposePath      string
+	behaviorsEnvFile          string
+	behaviorsSkipVersionCheck bool
+)
+
+func init() {
+	behaviorsCmd.Args = cobra.NoArgs
+
+	behaviorsCmd.Flags().StringVarP(&behaviorsComposePath, "compose-file", "f", "", "Docker Compose .yaml file whose services provide the payloads (defaults to every local Coral image)")
+	behaviorsCmd.Flags().StringVar(&behaviorsEnvFile, "env-file", "", "Optional path to .env file to use for compose substitutions")
+	behaviorsCmd.Flags().BoolVar(&behaviorsSkipVersionCheck, "skip-version-check", false, "Skip coral.version compatibility check between CLI and images")
+
+	behaviorsCmd.RegisterFlagCompletionFunc("compose-file", completeComposeFiles)
+}
+
+var behaviorsCmd = &cobra.Command{
+	Use:   "behaviors",
+	Short: "Lists the behavior-tree nodes, behaviors and interfaces available from Coral images",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// a ctrl+c during extraction stops the probe copy and removes the probe container
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return behaviors(ctx, coral.BehaviorsOptions{
+			ComposePath:      behaviorsComposePath,
+			EnvFile:          behaviorsEnvFile,
+			SkipVersionCheck: behaviorsSkipVersionCheck,
+		})
+	},
+}
+
+func behaviors(ctx context.Context, opts coral.BehaviorsOptions) error {
+	result, err := coral.NewLauncher(Version).Behaviors(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if outputFormat.Structured() {
+		return printResult(result)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tNAME\tTYPE\tPROVIDER\tBTCPP\tROS\tNOTES")
+	for _, b := range result {
+		var notes []string
+		if b.Shadowed {
+			notes = append(notes, fmt.Sprintf("shadowed by %s (%s)", b.ShadowedBy, b.Decision))
+		}
+		if len(b.Clashes) > 0 {
+			notes = append(notes, "also registered by "+strings.Join(b.Clashes, ", "))
+		}
+		provider := b.Image
+		if b.Service != "" {
+			provider = fmt.Sprintf("%s (%s)", b.Service, b.Image)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", b.Kind, b.Name, b.Type, provider, b.BtcppVersion, b.RosDistro, strings.Join(notes, "; "))
+	}
+	return w.Flush()
+}
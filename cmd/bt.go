@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"coral_cli/internal/logging"
+	"coral_cli/pkg/coral"
+)
+
+var (
+	btComposePath      string
+	btEnvFile          string
+	btExecutor         string
+	btConflictPolicy   string
+	btSkipVersionCheck bool
+)
+
+func init() {
+	btValidateCmd.Args = cobra.ExactArgs(1)
+
+	btValidateCmd.Flags().StringVarP(&btComposePath, "compose-file", "f", "", "Path to Docker Compose .yaml file whose payloads would be injected")
+	btValidateCmd.Flags().StringVar(&btEnvFile, "env-file", "", "Optional path to .env file to use for compose substitutions")
+	btValidateCmd.Flags().StringVar(&btExecutor, "executor", "", "Executor service to check the tree for (defaults to the compose file's only executor)")
+	btValidateCmd.Flags().StringVar(&btConflictPolicy, "conflict", string(coral.ConflictPriority), "How libraries with the same name from different payloads are resolved (priority, newest, error)")
+	btValidateCmd.Flags().BoolVar(&btSkipVersionCheck, "skip-version-check", false, "Skip coral.version compatibility check between CLI and images")
+
+	btValidateCmd.RegisterFlagCompletionFunc("compose-file", completeComposeFiles)
+
+	btCmd.AddCommand(btValidateCmd)
+}
+
+var btCmd = &cobra.Command{
+	Use:   "bt",
+	Short: "Works with the behavior trees run by Coral executors",
+}
+
+var btValidateCmd = &cobra.Command{
+	Use:   "validate <tree.xml>",
+	Short: "Checks that the behaviors injected into an executor provide every node and port of a behavior tree",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// a ctrl+c during extraction stops the probe copy and removes the probe container
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		result, err := coral.NewLauncher(Version).ValidateTree(ctx, args[0], coral.TreeOptions{
+			ComposePath:      btComposePath,
+			EnvFile:          btEnvFile,
+			Executor:         btExecutor,
+			ConflictPolicy:   coral.ConflictPolicy(btConflictPolicy),
+			SkipVersionCheck: btSkipVersionCheck,
+		})
+		if result == nil {
+			return err
+		}
+		for _, problem := range result.Problems {
+			logging.Failuref("%s", problem)
+		}
+		for _, problem := range result.Unknown {
+			logging.Warnf("%s", problem)
+		}
+		if len(result.Unlisted) > 0 {
+			logging.Warnf("Nodes of %s are unknown: they ship no node manifest", strings.Join(result.Unlisted, ", "))
+		}
+		if err == nil && len(result.Unknown) > 0 {
+			logging.Warnf("Behavior tree %s has no known problems, but %d nodes could not be checked", args[0], len(result.Unknown))
+		} else if err == nil {
+			logging.Successf("Behavior tree %s uses only nodes available to executor %s", args[0], logging.BoldMagenta(result.Executor))
+		}
+		if perr := printResult(result); perr != nil {
+			return perr
+		}
+		if err != nil {
+			// every problem has been reported above
+			if !outputFormat.Structured() {
+				logging.Failuref("%s", err)
+			}
+			return exitWith(cmd, 1)
+		}
+		return nil
+	},
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"coral_cli/internal/docker"
+)
+
+// runs a docker listing subcommand with one JSON object per line and keeps the rows whose field starts with "coral"; used for structured output of the commands that otherwise pass straight through to docker
+func coralRowsJSON(ctx context.Context, subcommand string, args []string, field string) ([]map[string]any, error) {
+	allArgs := append([]string{subcommand, "--format", "{{json .}}"}, args...)
+	cmd := docker.CommandContext(ctx, docker.Query, allArgs...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	rows := []map[string]any{}
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		var row map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+			continue
+		}
+		if value, _ := row[field].(string); strings.HasPrefix(value, "coral") {
+			rows = append(rows, row)
+		}
+	}
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("docker %s: %w", subcommand, err)
+	}
+	return rows, nil
+}
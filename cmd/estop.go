@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"coral_cli/internal/logging"
+	"coral_cli/pkg/coral"
+)
+
+var (
+	estopSelector selectorFlags
+	estopKill     bool
+)
+
+func init() {
+	estopCmd.Args = cobra.NoArgs
+
+	addSelectorFlags(estopCmd, &estopSelector, "stop")
+	addDryRunFlag(estopCmd, &estopSelector)
+	estopCmd.Flags().BoolVar(&estopKill, "kill", false, "Kill executors and drivers instead of pausing them")
+}
+
+var estopCmd = &cobra.Command{
+	Use:   "estop",
+	Short: "Immediately pauses (or kills) every executor and driver of running Coral instances",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		mode := coral.EStopPause
+		if estopKill {
+			mode = coral.EStopKill
+		}
+		return estop(cmd, &estopSelector, mode)
+	},
+}
+
+// halts the selected instances, every instance when no criteria are given
+func estop(cmd *cobra.Command, sel *selectorFlags, mode coral.EStopMode) error {
+	ctx := cmd.Context()
+	selector, err := sel.selector()
+	if err != nil {
+		return err
+	}
+	if sel.dryRun {
+		instances, err := sel.selectInstances(ctx, false)
+		if err != nil {
+			return err
+		}
+		return printDryRun(string(mode), instances)
+	}
+
+	event, err := coral.EStop(ctx, coral.EStopOptions{Mode: mode, Selector: selector})
+	if event != nil {
+		verb := "Paused"
+		if mode == coral.EStopKill {
+			verb = "Killed"
+		}
+		for _, t := range event.Instances {
+			if n := countContainers(t.Containers); n > 0 {
+				logging.Warnf("%s %d container(s) of %s", verb, n, logging.BoldMagenta(t.Instance))
+			}
+		}
+		if len(event.Instances) > 0 {
+			logging.Infof("Run `coral resume` to continue, or `coral shutdown` to stop for good")
+		}
+		if perr := printResult(event); perr != nil {
+			return perr
+		}
+	}
+	if err != nil && event != nil {
+		// the event, with the error of every container that could not be halted, has been printed above
+		if !outputFormat.Structured() {
+			logging.Failuref("%s", err)
+		}
+		return exitWith(cmd, 1)
+	}
+	return err
+}
+
+func countContainers(byProfile map[string][]string) int {
+	n := 0
+	for _, ids := range byProfile {
+		n += len(ids)
+	}
+	return n
+}
@@ -2,30 +2,43 @@ package cmd
 
 import (
 	"bufio"
-	"os/exec"
+	"context"
+	"fmt"
+	"os"
 	"strings"
 
 	"github.com/spf13/cobra"
+
+	"coral_cli/internal/docker"
 )
 
 var imagesCmd = &cobra.Command{
 	Use:   "images",
 	Short: "List only coral-prefixed Docker images",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return showCoralImages(args)
+		return showCoralImages(cmd.Context(), args)
 	},
 }
 
-func showCoralImages(args []string) error {
+func showCoralImages(ctx context.Context, args []string) error {
+	if outputFormat.Structured() {
+		rows, err := coralRowsJSON(ctx, "images", args, "Repository")
+		if err != nil {
+			return err
+		}
+		return printResult(rows)
+	}
+
 	allArgs := append([]string{"images"}, args...)
-	cmd := exec.Command("docker", allArgs...)
+	cmd := docker.CommandContext(ctx, docker.Query, allArgs...)
+	cmd.Stderr = os.Stderr
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		panic(err)
+		return err
 	}
 
 	if err := cmd.Start(); err != nil {
-		panic(err)
+		return fmt.Errorf("running docker images: %w", err)
 	}
 
 	scanner := bufio.NewScanner(stdout)
@@ -34,11 +47,13 @@ func showCoralImages(args []string) error {
 		line := scanner.Text()
 		if first || strings.HasPrefix(line, "coral") {
 			// keep the header and lines starting with "coral"
-			println(line)
+			fmt.Println(line)
 		}
 		first = false
 	}
 
-	cmd.Wait()
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("docker images failed: %w", err)
+	}
 	return nil
 }
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"coral_cli/internal/logging"
+)
+
+// escalating ctrl+c handling for a foreground instance: the first interrupt cancels ctx so the launch or log tail ends and a graceful stop begins, the second closes escalate so whatever is still running is killed, and the third cancels abandon so cleanup is given up and the instance left for coral prune. A fourth interrupt terminates coral itself
+type interruptStages struct {
+	ctx      context.Context
+	escalate chan struct{}
+	abandon  context.Context
+	stop     func()
+}
+
+func watchInterrupts() *interruptStages {
+	ctx, cancel := context.WithCancel(context.Background())
+	abandon, cancelAbandon := context.WithCancel(context.Background())
+	stages := &interruptStages{ctx: ctx, escalate: make(chan struct{}), abandon: abandon}
+
+	signalChan := make(chan os.Signal, 3)
+	signal.Notify(signalChan, os.Interrupt, syscall.SIGTERM)
+	finished := make(chan struct{})
+	stages.stop = func() {
+		signal.Stop(signalChan)
+		close(finished)
+		cancel()
+		cancelAbandon()
+	}
+
+	go func() {
+		defer signal.Stop(signalChan)
+		for n := 1; n <= 3; n++ {
+			select {
+			case <-signalChan:
+			case <-finished:
+				return
+			}
+			switch n {
+			case 1:
+				cancel()
+			case 2:
+				logging.Warnf("Second interrupt — killing remaining services (Ctrl+C again to abandon cleanup)")
+				close(stages.escalate)
+			case 3:
+				logging.Warnf("Third interrupt — abandoning cleanup")
+				cancelAbandon()
+			}
+		}
+	}()
+	return stages
+}
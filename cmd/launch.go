@@ -2,44 +2,43 @@ package cmd
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
-	"os/exec"
-	"os/signal"
 	"path/filepath"
+	"slices"
 	"strings"
-	"sync"
-	"sync/atomic"
-	"syscall"
 	"time"
 
-	"github.com/google/uuid"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"gopkg.in/yaml.v3"
 
-	"coral_cli/internal/cleanup"
-	"coral_cli/internal/compose"
-	"coral_cli/internal/health"
-	"coral_cli/internal/libs"
 	"coral_cli/internal/logging"
-	"coral_cli/internal/registry"
-	"coral_cli/internal/util"
+	"coral_cli/pkg/coral"
 )
 
 var (
-	launchComposePath   string
-	launchEnvFile       string
-	launchHandle        string
-	launchGroup         string
-	launchDetached      bool
-	launchKill          bool
-	launchExecutorDelay float32
-	launchProfiles      []string
-	launchLibDir        string
+	launchComposePath      string
+	launchEnvFile          string
+	launchHandle           string
+	launchReplace          bool
+	launchGroup            string
+	launchLabels           []string
+	launchDetached         bool
+	launchKill             bool
+	launchExecutorDelay    float32
+	launchProfiles         []string
+	launchLibDir           string
 	launchHealthTimeout    float32
 	launchSkipVersionCheck bool
+	launchStopTimeout      []string
+	launchStopSignal       string
+	launchInterlock        string
+	launchManualResume     bool
+	launchExitOnDone       bool
+	launchTimeout          time.Duration
+	launchInjectScope      string
+	launchConflict         string
 )
 
 func init() {
@@ -47,10 +46,20 @@ func init() {
 
 	launchCmd.Flags().StringVarP(&launchComposePath, "compose-file", "f", "", "Path to Docker Compose .yaml file to start services")
 	launchCmd.Flags().StringVar(&launchEnvFile, "env-file", "", "Optional path to .env file to use for compose file substitutions")
-	launchCmd.Flags().StringVar(&launchHandle, "handle", "", "Optional handle for this instance")
+	launchCmd.Flags().StringVar(&launchHandle, "handle", "", "Optional handle for this instance; must be unique unless --replace is given")
+	launchCmd.Flags().BoolVar(&launchReplace, "replace", false, "Gracefully shut down the instance already using --handle before launching (uses --kill, --stop-timeout and --stop-signal)")
 	launchCmd.Flags().StringVarP(&launchGroup, "group", "g", "coral", "Optional group for this instance")
+	launchCmd.Flags().StringSliceVarP(&launchLabels, "label", "l", []string{}, "Label for this instance as key=value, set on every container and usable in selectors; repeat for several")
 	launchCmd.Flags().BoolVarP(&launchDetached, "detached", "d", false, "Launch in detached mode")
-	launchCmd.Flags().BoolVar(&launchKill, "kill", true, "Forcefully kills instances before removing them")
+	launchCmd.Flags().BoolVar(&launchKill, "kill", false, "Forcefully kills instances before removing them instead of stopping them gracefully")
+	launchCmd.Flags().StringSliceVar(&launchStopTimeout, "stop-timeout", []string{"10"}, "Seconds each profile is given to stop gracefully on shutdown before it is killed, either for all profiles or as profile=seconds (e.g. executors=30)")
+	launchCmd.Flags().StringVar(&launchStopSignal, "stop-signal", "", "Signal sent to stop each container gracefully on shutdown (e.g. SIGINT); defaults to each service's stop_signal")
+	launchCmd.Flags().StringVar(&launchInterlock, "interlock", "pause", "What to do with executors while a driver or skillset labelled coral.critical=true is unhealthy or exited: pause, kill or off")
+	launchCmd.Flags().BoolVar(&launchManualResume, "manual-resume", false, "Keep executors halted by the interlock until `coral resume` is run, even after critical services recover")
+	launchCmd.Flags().BoolVar(&launchExitOnDone, "exit-on-executors-done", false, "Shut the instance down once every executor has exited, and exit with the first non-zero executor exit code")
+	launchCmd.Flags().DurationVar(&launchTimeout, "timeout", 0, "Shut the instance down and exit with status 124 if it is still running after this long (e.g. 30m); unbounded when 0")
+	launchCmd.Flags().StringVar(&launchInjectScope, "inject-scope", string(coral.ScopeInstance), "Which payloads' libraries are injected into executors: instance (only this instance's payloads), group (payloads of any instance in the same group) or global (every payload in the lib dir)")
+	launchCmd.Flags().StringVar(&launchConflict, "conflict", string(coral.ConflictPriority), "How libraries with the same name but different content from two payloads are resolved: priority (highest coral.priority label or x-coral priority wins, then the newer file), newest (the newer file wins) or error (the launch fails)")
 	launchCmd.Flags().Float32Var(&launchExecutorDelay, "executor-delay", 0.0, "Additional delay in seconds after health checks pass before starting executors")
 	launchCmd.Flags().StringSliceVarP(&launchProfiles, "profile", "p", []string{}, "List of profiles to launch (drivers, skillsets, executors); if not specified, all profiles will be launched")
 	launchCmd.Flags().StringVar(&launchLibDir, "lib-dir", "", "Override CORAL_LIB path (takes precedence over $CORAL_LIB environment variable)")
@@ -72,49 +81,7 @@ func init() {
 		return nil, cobra.ShellCompDirectiveNoFileComp
 	}
 
-	launchCmd.RegisterFlagCompletionFunc("compose-file", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		dir := "."
-		if strings.Contains(toComplete, string(os.PathSeparator)) {
-			dir = filepath.Dir(toComplete)
-			if dir == "" {
-				dir = "."
-			}
-		}
-		files, err := os.ReadDir(dir)
-		if err != nil {
-			return nil, cobra.ShellCompDirectiveError
-		}
-		var suggestions []string
-		for _, f := range files {
-			entry := filepath.Join(dir, f.Name())
-			display := entry
-			if f.IsDir() {
-				display += string(os.PathSeparator)
-			}
-			if !strings.HasPrefix(display, toComplete) {
-				continue
-			}
-			if f.IsDir() {
-				suggestions = append(suggestions, display)
-				continue
-			}
-			if !strings.HasSuffix(f.Name(), ".yaml") && !strings.HasSuffix(f.Name(), ".yml") {
-				continue
-			}
-			content, err := os.ReadFile(entry)
-			if err != nil {
-				continue
-			}
-			var doc map[string]any
-			if err := yaml.Unmarshal(content, &doc); err != nil {
-				continue
-			}
-			if _, ok := doc["services"]; ok {
-				suggestions = append(suggestions, display)
-			}
-		}
-		return suggestions, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
-	})
+	launchCmd.RegisterFlagCompletionFunc("compose-file", completeComposeFiles)
 
 	launchCmd.RegisterFlagCompletionFunc("profile", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		profiles := []string{"drivers", "skillsets", "executors"}
@@ -128,594 +95,311 @@ func init() {
 	})
 }
 
-var launchCmd = &cobra.Command{
-	Use:   "launch",
-	Short: "Launches Coral instances",
-	RunE: func(cmd *cobra.Command, args []string) error {
-		return launch(launchComposePath, launchEnvFile, launchHandle, launchGroup,
-			launchDetached, launchKill, launchExecutorDelay, launchHealthTimeout,
-			launchLibDir, launchProfiles, launchSkipVersionCheck)
-	},
-}
-
-func launch(composePath, envFile, handle, group string, detached, kill bool,
-	executorDelay, healthTimeout float32, libDirOverride string, profilesToStart []string, skipVersionCheck bool) error {
-
-	signalChan := make(chan os.Signal, 1)
-	signal.Notify(signalChan, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-	var receivedSignal atomic.Bool
-	go func() {
-		<-signalChan
-		receivedSignal.Store(true)
-	}()
-
-	// load environment
-	env := make(map[string]string)
-	resolvedEnvFile, err := util.ResolveEnvFile(envFile)
-	if err != nil {
-		return fmt.Errorf("resolving env file: %w", err)
-	}
-	if resolvedEnvFile != "" {
-		env, err = compose.LoadEnvFile(resolvedEnvFile)
-		if err != nil {
-			return fmt.Errorf("loading .env: %w", err)
-		}
-	}
-	for _, e := range os.Environ() {
-		parts := strings.SplitN(e, "=", 2)
-		if len(parts) == 2 {
-			if _, exists := env[parts[0]]; !exists {
-				env[parts[0]] = parts[1]
-			}
-		}
-	}
-
-	// resolve compose file
-	resolvedComposePath, err := util.ResolveComposeFile(composePath)
-	if err != nil {
-		return err
-	}
-	parsedCompose, err := compose.ParseCompose(resolvedComposePath, env)
-	if err != nil {
-		return fmt.Errorf("parsing compose file: %w", err)
-	}
-
-	// resolve lib path
-	var libPath string
-	if libDirOverride != "" {
-		libPath = libDirOverride
-	} else {
-		libPath = env["CORAL_LIB"]
-	}
-	if strings.TrimSpace(libPath) == "" {
-		libPath, err = filepath.Abs("./lib")
-		if err != nil {
-			return fmt.Errorf("resolving ./lib: %w", err)
-		}
-		if _, err := os.Stat(libPath); os.IsNotExist(err) {
-			if err := os.Mkdir(libPath, 0755); err != nil {
-				return fmt.Errorf("creating lib dir: %w", err)
-			}
-		}
-	} else {
-		if _, err := os.Stat(libPath); os.IsNotExist(err) {
-			return fmt.Errorf("lib dir %q does not exist", libPath)
-		}
-	}
-
-	// when CORAL runs inside Docker, Docker volume mounts in compose files need host paths; note that docker cp operations stream through the socket so no longer require special handling
-	isDocker := env["CORAL_IS_DOCKER"]
-	var hostLibPath string
-	if isDocker == "true" {
-		var ok bool
-		hostLibPath, ok = env["CORAL_HOST_LIB"]
-		if !ok || strings.TrimSpace(hostLibPath) == "" {
-			return fmt.Errorf("CORAL_HOST_LIB is required when CORAL_IS_DOCKER=true")
+// suggests directories and .yaml/.yml files that contain a services section
+func completeComposeFiles(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	dir := "."
+	if strings.Contains(toComplete, string(os.PathSeparator)) {
+		dir = filepath.Dir(toComplete)
+		if dir == "" {
+			dir = "."
 		}
 	}
-
-	if err := checkImagesLocal(parsedCompose, skipVersionCheck); err != nil {
-		return fmt.Errorf("checking images: %w", err)
-	}
-
-	uid := uuid.New()
-	instanceName := fmt.Sprintf("coral-%x", uid[:4])
-	fmt.Println(logging.Info("Launching new instance " + logging.BoldMagentaHi(instanceName)))
-
-	// load (or create) the persistent registry
-	reg, err := registry.Load(libPath)
+	files, err := os.ReadDir(dir)
 	if err != nil {
-		return fmt.Errorf("loading registry: %w", err)
+		return nil, cobra.ShellCompDirectiveError
 	}
-
-	// declared here so the deferred abort below can reference it even if launch fails before writeComposeToDisk is reached
-	outputPath := filepath.Join(libPath, "compose", instanceName+".yaml")
-
-	// guard: if launch fails before the instance is fully handed off to the run functions (which have their own cleanup), abort removes any staging dirs and registry records written so far
-	launched := false
-	defer func() {
-		if !launched {
-			cleanup.AbortInstance(instanceName, libPath, outputPath, reg)
+	var suggestions []string
+	for _, f := range files {
+		entry := filepath.Join(dir, f.Name())
+		display := entry
+		if f.IsDir() {
+			display += string(os.PathSeparator)
 		}
-	}()
-
-	mergedCompose, profilesMap, err := buildMergedCompose(
-		parsedCompose, libPath, hostLibPath, profilesToStart, instanceName, reg)
-	if err != nil {
-		return err
-	}
-
-	profiles := extractProfileNames(profilesMap)
-	servicesRaw, ok := mergedCompose["services"]
-	if !ok {
-		return fmt.Errorf("merged compose file has no 'services' section")
-	}
-	services, ok := servicesRaw.(map[string]interface{})
-	if !ok || len(services) == 0 {
-		return fmt.Errorf("merged compose file has no valid services")
-	}
-
-	if err := writeComposeToDisk(outputPath, mergedCompose); err != nil {
-		return err
-	}
-	if err := writeInstanceMetadata(instanceName, outputPath, libPath, handle, group, detached); err != nil {
-		return err
-	}
-
-	profiles = orderedProfiles(profiles)
-	if len(profiles) == 0 {
-		return fmt.Errorf("no valid profiles to run")
-	}
-
-	// past this point metadata is written; suppress the deferred abort and use RemoveInstanceFiles (which reads metadata) for any remaining cleanup
-	launched = true
-
-	if receivedSignal.Load() {
-		fmt.Printf("\n%s\n", logging.Warning(fmt.Sprintf("Interrupt during init — cleaning up %s...", logging.BoldMagenta(instanceName))))
-		cleanup.RemoveInstanceFiles(instanceName)
-		fmt.Println(logging.Success("Done"))
-		return nil
-	}
-
-	signal.Reset(os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	if detached {
-		// give the health gate a context that the user can cancel with ctrl+c
-		dCtx, dCancel := context.WithCancel(context.Background())
-		defer dCancel()
-		dSigCh := make(chan os.Signal, 1)
-		signal.Notify(dSigCh, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-		go func() {
-			select {
-			case <-dSigCh:
-				dCancel()
-				signal.Stop(dSigCh)
-			case <-dCtx.Done():
-			}
-		}()
-		if err := runDetached(dCtx, profiles, instanceName, outputPath, executorDelay, healthTimeout, profilesMap, reg); err != nil {
-			if dCtx.Err() == nil {
-				fmt.Println(logging.Warning(fmt.Sprintf("Launch failed — cleaning up %s...", logging.BoldMagenta(instanceName))))
-				_ = cleanup.StopCompose(instanceName, outputPath, true, profiles)
-				_ = cleanup.RemoveInstanceFiles(instanceName)
-			}
-			return err
-		}
-		return nil
-	}
-	return runForeground(profiles, instanceName, outputPath, kill, executorDelay, healthTimeout, profilesMap, reg)
-}
-
-var validProfiles = map[string]bool{"drivers": true, "skillsets": true, "executors": true}
-
-func checkImagesLocal(cf *compose.ComposeFile, skipVersionCheck bool) error {
-	selfMajor, err := parseMajorVersion(Version)
-	checkVersion := !skipVersionCheck && err == nil // skip for dev builds or when flag is set
-
-	for name, svc := range cf.Services {
-		raw, ok := svc["image"]
-		if !ok || raw == nil {
-			return fmt.Errorf("missing 'image' in service %s", name)
+		if !strings.HasPrefix(display, toComplete) {
+			continue
 		}
-		image, ok := raw.(string)
-		if !ok {
-			return fmt.Errorf("expected string for 'image' in service %s", name)
+		if f.IsDir() {
+			suggestions = append(suggestions, display)
+			continue
 		}
-		if _, err := libs.GetImageID(image); err != nil {
-			return fmt.Errorf("checking image %s for service %s: %w", image, name, err)
+		if !strings.HasSuffix(f.Name(), ".yaml") && !strings.HasSuffix(f.Name(), ".yml") {
+			continue
 		}
-		labels, err := libs.GetImageLabels(image)
+		content, err := os.ReadFile(entry)
 		if err != nil {
-			return fmt.Errorf("reading labels for service %s: %w", name, err)
-		}
-		profile := labels["coral.profile"]
-		if profile == "" {
-			return fmt.Errorf("image %s (service %s) is missing required label coral.profile", image, name)
+			continue
 		}
-		if !validProfiles[profile] {
-			return fmt.Errorf("image %s (service %s) has invalid coral.profile %q: must be one of drivers, skillsets, executors", image, name, profile)
+		var doc map[string]any
+		if err := yaml.Unmarshal(content, &doc); err != nil {
+			continue
 		}
-		if checkVersion {
-			coralVer := labels["coral.version"]
-			if coralVer == "" {
-				return fmt.Errorf("image %s (service %s) is missing required label coral.version", image, name)
-			}
-			imageMajor, err := parseMajorVersion(coralVer)
-			if err != nil {
-				return fmt.Errorf("image %s (service %s) has unparseable coral.version %q: %w", image, name, coralVer, err)
-			}
-			if imageMajor != selfMajor {
-				return fmt.Errorf("image %s (service %s) coral.version %q is incompatible with CLI version %s (major %d != %d)",
-					image, name, coralVer, Version, imageMajor, selfMajor)
-			}
+		if _, ok := doc["services"]; ok {
+			suggestions = append(suggestions, display)
 		}
 	}
-	return nil
+	return suggestions, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
 }
 
-// extracts library artifacts from each service image, records them in the registry, and builds the merged compose map
-func buildMergedCompose(cf *compose.ComposeFile, lib, hostLib string,
-	profilesToStart []string, instanceName string, reg *registry.Registry,
-) (compose.RawCompose, map[string][]string, error) {
-
-	rawCompose, err := cf.ToMap()
-	if err != nil {
-		return nil, nil, fmt.Errorf("converting compose to map: %w", err)
-	}
-	rawServices := rawCompose["services"].(map[string]interface{})
-	merged := compose.RawCompose{"services": map[string]interface{}{}}
-	profilesMap := map[string][]string{}
-
-	// hostLib is used only for volume-mount path rewriting in compose files
-	imageLib := lib
-	if hostLib != "" {
-		imageLib = hostLib
-	}
-	_ = imageLib // used below for volume path rewriting
-
-	for name, svc := range cf.Services {
-		image := svc["image"].(string)
-		labels, err := libs.GetImageLabels(image)
+var launchCmd = &cobra.Command{
+	Use:   "launch",
+	Short: "Launches Coral instances",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		stopOpts, err := stopOptions(launchKill, launchStopTimeout, launchStopSignal)
 		if err != nil {
-			return nil, nil, fmt.Errorf("reading labels for service %s: %w", name, err)
-		}
-		profile := labels["coral.profile"] // already validated non-empty and valid in checkImagesLocal
-
-		if len(profilesToStart) > 0 {
-			matched := false
-			for _, p := range profilesToStart {
-				if p == profile {
-					matched = true
-					break
-				}
-			}
-			if !matched {
-				continue
-			}
+			return err
 		}
-		profilesMap[profile] = append(profilesMap[profile], name)
-
-		stagingDir, imageID, err := libs.ExtractLibraries(image, name, lib)
+		interlock, err := interlockOptions(launchInterlock, launchManualResume)
 		if err != nil {
-			return nil, nil, fmt.Errorf("extracting %s for service %s: %w", image, name, err)
-		}
-
-		if err := reg.RecordExtraction(imageID, stagingDir, imageID, instanceName, labels["coral.btcpp_version"], labels["coral.ros_distro"]); err != nil {
-			fmt.Println(logging.Warning(fmt.Sprintf("recording extraction for %s: %v", name, err)))
-		}
-
-		fmt.Println(logging.Info(fmt.Sprintf(
-			"Extracted interfaces from %s for %s", image, logging.BoldMagenta(name))))
-
-		// merge docker.yaml from the staging directory into the service config
-		baseSvc := rawServices[name].(map[string]interface{})
-		extractedPath := filepath.Join(stagingDir, "docker.yaml")
-		var mergedSvc map[string]interface{}
-		if _, err := os.Stat(extractedPath); err == nil {
-			extracted, err := compose.LoadRawYAML(extractedPath)
-			if err != nil {
-				return nil, nil, fmt.Errorf("loading extracted compose for %s: %w", name, err)
-			}
-			mergedSvc = compose.MergeServiceConfigs(baseSvc, extracted)
-		} else {
-			mergedSvc = baseSvc
+			return err
 		}
-
-		// resolve and inject device mappings from devices.yaml
-		devicesPath := filepath.Join(stagingDir, "devices.yaml")
-		if _, err := os.Stat(devicesPath); err == nil {
-			df, err := compose.LoadDevicesFile(devicesPath)
-			if err != nil {
-				return nil, nil, fmt.Errorf("loading devices.yaml for %s: %w", name, err)
-			}
-			devPaths, err := compose.ResolveDevicePaths(df, name)
-			if err != nil {
-				return nil, nil, err
-			}
-			if len(devPaths) > 0 {
-				existing, _ := mergedSvc["devices"].([]interface{})
-				for _, p := range devPaths {
-					existing = append(existing, p)
-				}
-				mergedSvc["devices"] = existing
-				fmt.Println(logging.Info(fmt.Sprintf(
-					"Mapped %d device(s) for %s", len(devPaths), logging.BoldMagenta(name))))
-			}
+		labels, err := parseLabels(launchLabels)
+		if err != nil {
+			return err
 		}
-
-		// rewrite relative host volume paths to absolute - and when CORAL is running inside Docker, rebase them onto hostLib so the Docker daemon can reach them
-		if volumes, ok := mergedSvc["volumes"].([]interface{}); ok {
-			for i, v := range volumes {
-				volStr, ok := v.(string)
-				if !ok {
-					continue
-				}
-				parts := strings.SplitN(volStr, ":", 2)
-				if len(parts) != 2 {
-					continue
-				}
-				hostPath := parts[0]
-				if filepath.IsAbs(hostPath) || strings.HasPrefix(hostPath, "${") {
-					// if the path is absolute and starts with libPath, rebase onto hostLib
-					if hostLib != "" && strings.HasPrefix(hostPath, lib) {
-						hostPath = hostLib + hostPath[len(lib):]
-						volumes[i] = fmt.Sprintf("%s:%s", hostPath, parts[1])
-					}
-					continue
-				}
-				absPath, err := filepath.Abs(hostPath)
-				if err == nil {
-					volumes[i] = fmt.Sprintf("%s:%s", absPath, parts[1])
-				}
-			}
+		foreground := foregroundOptions{exitOnDone: launchExitOnDone, timeout: launchTimeout}
+		if err := foreground.validate(launchDetached, interlock); err != nil {
+			return err
 		}
+		replaceOpts := stopOpts
+		replaceOpts.Progress = printStopProgress
+		return launch(cmd, coral.LaunchOptions{
+			ComposePath:      launchComposePath,
+			EnvFile:          launchEnvFile,
+			Handle:           launchHandle,
+			Replace:          launchReplace,
+			ReplaceOptions:   replaceOpts,
+			Group:            launchGroup,
+			Labels:           labels,
+			Detached:         launchDetached,
+			ExecutorDelay:    time.Duration(launchExecutorDelay * float32(time.Second)),
+			HealthTimeout:    time.Duration(launchHealthTimeout * float32(time.Second)),
+			Profiles:         launchProfiles,
+			LibDir:           launchLibDir,
+			SkipVersionCheck: launchSkipVersionCheck,
+			InjectionScope:   coral.InjectionScope(launchInjectScope),
+			ConflictPolicy:   coral.ConflictPolicy(launchConflict),
+		}, stopOpts, interlock, foreground)
+	},
+}
 
-		mergedSvc["profiles"] = []interface{}{profile}
-		merged["services"].(map[string]interface{})[name] = mergedSvc
+// parses --interlock and --manual-resume; nil disables the interlock
+func interlockOptions(mode string, manualResume bool) (*coral.InterlockOptions, error) {
+	switch mode {
+	case "off":
+		return nil, nil
+	case string(coral.EStopPause), string(coral.EStopKill):
+		return &coral.InterlockOptions{Mode: coral.EStopMode(mode), ManualResume: manualResume}, nil
 	}
-
-	return merged, profilesMap, nil
+	return nil, &coral.Error{Code: coral.ErrCodeInvalidInput, Err: fmt.Errorf("invalid --interlock %q: must be pause, kill or off", mode)}
 }
 
-// performs the three-phase executor launch:
-//  1. docker compose create  — allocate containers without starting them
-//  2. InjectLibraries        — copy behavior/interface .so files into each container, sourced from all staging dirs
-//  3. docker compose start   — start the containers
-func createAndStartExecutors(instanceName, composePath string, executorServices []string,
-	reg *registry.Registry) error {
+// how a foreground instance ends besides an interrupt
+type foregroundOptions struct {
+	exitOnDone bool          // shut down once every executor has exited
+	timeout    time.Duration // shut down once this long has passed since the launch began; unbounded when zero
+}
 
-	createArgs := []string{"compose", "-p", instanceName, "-f", composePath, "--profile", "executors", "create"}
-	createCmd := exec.Command("docker", createArgs...)
-	createCmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
-	createCmd.Stdout = os.Stdout
-	createCmd.Stderr = os.Stderr
-	if err := createCmd.Run(); err != nil {
-		return fmt.Errorf("creating executor containers: %w", err)
+// rejects foreground options that cannot apply to the launch; an interlock that kills executors would make every executor count as exited, so it cannot be combined with exitOnDone
+func (f foregroundOptions) validate(detached bool, interlock *coral.InterlockOptions) error {
+	if f.timeout < 0 {
+		return &coral.Error{Code: coral.ErrCodeInvalidInput, Err: fmt.Errorf("invalid --timeout %s: must not be negative", f.timeout)}
 	}
-
-	allExtractions := reg.AllExtractions()
-
-	for _, svc := range executorServices {
-		containerID, err := health.GetContainerIDForService(instanceName, svc)
-		if err != nil || containerID == "" {
-			return fmt.Errorf("locating container for executor service %s: %w", svc, err)
-		}
-
-		execLabels, err := libs.GetContainerLabels(containerID)
-		if err != nil {
-			return fmt.Errorf("reading labels for executor %s: %w", svc, err)
-		}
-		execBtcpp := execLabels["coral.btcpp_version"]
-		execRos := execLabels["coral.ros_distro"]
-
-		compatibleDirs := make(map[string]string)
-		for imageID, rec := range allExtractions {
-			var reasons []string
-			if rec.BtcppVersion != execBtcpp {
-				reasons = append(reasons, fmt.Sprintf("BT.CPP version mismatch %q != %q", rec.BtcppVersion, execBtcpp))
-			}
-			if rec.RosDistro != execRos {
-				reasons = append(reasons, fmt.Sprintf("ROS distro mismatch %q != %q", rec.RosDistro, execRos))
-			}
-			if len(reasons) > 0 {
-				fmt.Println(logging.Warning(fmt.Sprintf(
-					"Cowardly refusing to inject libraries from %s into executor %s: %s",
-					rec.PayloadID, svc, strings.Join(reasons, ", "),
-				)))
-				continue
-			}
-			compatibleDirs[imageID] = rec.StagingDir
-		}
-
-		injected, err := libs.InjectLibraries(containerID, compatibleDirs)
-		if err != nil {
-			return fmt.Errorf("injecting libraries into %s: %w", svc, err)
-		}
-		if err := reg.RecordInjection(containerID, instanceName, injected); err != nil {
-			fmt.Println(logging.Warning(fmt.Sprintf("recording injection for %s: %v", svc, err)))
-		}
-		active := 0
-		for _, l := range injected {
-			if !l.Shadowed {
-				active++
-			}
-		}
-		fmt.Println(logging.Info(fmt.Sprintf(
-			"Injected %d libraries into executor %s", active, logging.BoldMagenta(svc))))
+	if detached && (f.exitOnDone || f.timeout > 0) {
+		return &coral.Error{Code: coral.ErrCodeInvalidInput, Err: fmt.Errorf("--exit-on-executors-done and --timeout only apply to foreground launches, not --detached")}
 	}
-
-	startArgs := append([]string{"compose", "-p", instanceName, "-f", composePath, "start"}, executorServices...)
-	startCmd := exec.Command("docker", startArgs...)
-	startCmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
-	startCmd.Stdout = os.Stdout
-	startCmd.Stderr = os.Stderr
-	return startCmd.Run()
+	if f.exitOnDone && interlock != nil && interlock.Mode == coral.EStopKill {
+		return &coral.Error{Code: coral.ErrCodeInvalidInput, Err: fmt.Errorf("--exit-on-executors-done cannot be combined with --interlock kill, which would end the instance on the first halt; use --interlock pause")}
+	}
+	return nil
 }
 
-func runDetached(ctx context.Context, profiles []string, instanceName, composePath string,
-	executorDelay, healthTimeout float32, profilesMap map[string][]string,
-	reg *registry.Registry) error {
+// exit status of coral when --timeout ends a foreground instance, as for timeout(1)
+const timeoutExitCode = 124
 
-	for _, profile := range profiles {
-		if profile == "executors" {
-			// gate on drivers + skillsets being healthy before touching executors (only if they implement health checks)
-			var depServices []string
-			for _, p := range []string{"drivers", "skillsets"} {
-				depServices = append(depServices, profilesMap[p]...)
-			}
-			if len(depServices) > 0 {
-				fmt.Println(logging.Info("Waiting for drivers and skillsets to become healthy..."))
-				timeout := time.Duration(healthTimeout * float32(time.Second))
-				if err := health.WaitForHealthy(ctx, instanceName, depServices, timeout); err != nil {
-					if ctx.Err() != nil {
-						return ctx.Err()
-					}
-					fmt.Println(logging.Warning(fmt.Sprintf("Health gate timed out: %v — proceeding anyway", err)))
-				}
-			}
-			if executorDelay > 0 {
-				fmt.Println(logging.Info(fmt.Sprintf("Waiting %.0fs before starting executors...", executorDelay)))
-				select {
-				case <-ctx.Done():
-					return ctx.Err()
-				case <-time.After(time.Duration(executorDelay) * time.Second):
-				}
-			}
-			if err := createAndStartExecutors(instanceName, composePath, profilesMap["executors"], reg); err != nil {
-				return fmt.Errorf("starting executors: %w", err)
-			}
-			continue
-		}
+func launch(cmd *cobra.Command, opts coral.LaunchOptions, stopOpts coral.ShutdownOptions, interlock *coral.InterlockOptions, foreground foregroundOptions) error {
+	// a ctrl+c during init, extraction or the health gate cancels the launch, which rolls back everything created so far
+	stages := watchInterrupts()
+	defer stages.stop()
 
-		fmt.Println(logging.Info(fmt.Sprintf("Starting %s (%d): %s",
-			logging.BoldMagenta(profile), len(profilesMap[profile]),
-			logging.BoldMagenta(fmt.Sprintf("%v", profilesMap[profile])))))
+	// --timeout bounds the launch as well as the foreground run
+	runCtx := stages.ctx
+	if foreground.timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(stages.ctx, foreground.timeout)
+		defer cancel()
+	}
 
-		cmd := exec.Command("docker", "compose", "-p", instanceName, "-f", composePath,
-			"--profile", profile, "up", "-d")
-		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("starting profile %s: %w", profile, err)
+	inst, err := coral.NewLauncher(Version).Launch(runCtx, opts)
+	if err != nil {
+		if stages.ctx.Err() == nil && runCtx.Err() != nil {
+			logging.Failuref("Launch did not finish within %s", foreground.timeout)
+			printError(err)
+			return exitWith(cmd, timeoutExitCode)
+		}
+		if stages.ctx.Err() != nil {
+			// an interrupted launch has already been rolled back and is not a failure of the CLI itself
+			printError(err)
+			return nil
 		}
+		return err
 	}
-	return nil
+	if err := printResult(newLaunchResult(inst)); err != nil {
+		return err
+	}
+	if opts.Detached {
+		return nil
+	}
+	code, err := runForeground(runCtx, inst, stages, stopOpts, interlock, foreground)
+	if err == nil && code != 0 {
+		return exitWith(cmd, code)
+	}
+	return err
 }
 
-func runForeground(profiles []string, instanceName, composePath string, kill bool,
-	executorDelay, healthTimeout float32, profilesMap map[string][]string,
-	reg *registry.Registry) error {
-
-	signalChan := make(chan os.Signal, 1)
-	signal.Notify(signalChan, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	ctx, cancel := context.WithCancel(context.Background())
+// structured result of a successful launch
+type launchResult struct {
+	Instance    string                         `json:"instance"`
+	Handle      string                         `json:"handle,omitempty"`
+	Group       string                         `json:"group,omitempty"`
+	Labels      map[string]string              `json:"labels,omitempty"`
+	Detached    bool                           `json:"detached"`
+	Scope       string                         `json:"injection_scope,omitempty"`
+	ComposeFile string                         `json:"compose_file"`
+	Services    map[string][]string            `json:"services"`
+	Injected    map[string][]coral.InjectedLib `json:"injected,omitempty"`
+}
 
-	var cleanupOnce sync.Once
-	doCleanup := func() {
-		cleanupOnce.Do(func() {
-			signal.Ignore(syscall.SIGINT, syscall.SIGTERM)
-			if err := cleanup.StopCompose(instanceName, composePath, kill, profiles); err != nil {
-				fmt.Println(logging.Warning(fmt.Sprintf("stopping compose: %v", err)))
-			}
-			if err := cleanup.RemoveInstanceFiles(instanceName); err != nil {
-				fmt.Println(logging.Failure(fmt.Sprintf("cleaning up files: %v", err)))
-			}
-			fmt.Println(logging.Success("Done"))
-		})
+func newLaunchResult(inst *coral.Instance) launchResult {
+	services := make(map[string][]string)
+	for _, profile := range inst.Profiles() {
+		services[profile] = inst.Services(profile)
+	}
+	return launchResult{
+		Instance:    inst.Name,
+		Handle:      inst.Handle,
+		Group:       inst.Group,
+		Labels:      inst.Labels,
+		Detached:    inst.Detached,
+		Scope:       inst.InjectionScope,
+		ComposeFile: inst.ComposeFile,
+		Services:    services,
+		Injected:    inst.Injected,
 	}
-	// cancel before doCleanup so the health monitor stops first (LIFO defer order)
-	defer doCleanup()
-	defer cancel()
-
-	shutdownChan := make(chan struct{})
-	// start the signal goroutine before runDetached so a ctrl+c during the health gate cancels the context and unblocks WaitForHealthy immediately
-	go func() {
-		<-signalChan
-		signal.Stop(signalChan)
-		cancel()
-		close(shutdownChan)
-	}()
+}
 
-	if err := runDetached(ctx, profiles, instanceName, composePath, executorDelay, healthTimeout, profilesMap, reg); err != nil {
-		if ctx.Err() != nil {
-			fmt.Printf("\n%s\n", logging.Warning(fmt.Sprintf("Interrupt received — shutting down %s...", logging.BoldMagenta(instanceName))))
-			return nil
+// tails the instance's logs until the user interrupts, every container exits, every executor exits (with exitOnDone) or runCtx times out, then shuts the instance down; further interrupts during the shutdown escalate it as described on interruptStages. While it runs, the interlock (unless nil) halts executors whenever a critical service fails. The returned code is the first non-zero exit code among the executors (in service order) when they have all exited, timeoutExitCode on timeout, and zero otherwise
+func runForeground(runCtx context.Context, inst *coral.Instance, stages *interruptStages, stopOpts coral.ShutdownOptions, interlock *coral.InterlockOptions, foreground foregroundOptions) (int, error) {
+	defer func() {
+		if !stopOpts.Kill {
+			logging.Infof("Stopping %s gracefully (Ctrl+C again to kill)...", logging.BoldMagenta(inst.Name))
 		}
-		return fmt.Errorf("starting profiles: %w", err)
-	}
+		stopOpts.Escalate = stages.escalate
+		stopOpts.Progress = printStopProgress
+		err := inst.Shutdown(stages.abandon, stopOpts)
+		if stages.abandon.Err() != nil {
+			logging.Warnf("Left %s running and marked it for cleanup; run `coral prune` to remove it", logging.BoldMagenta(inst.Name))
+			return
+		}
+		if err != nil {
+			logging.Failuref("%v", err)
+		}
+		logging.Successf("Done")
+	}()
 
 	// start health monitor after all profiles are running
-	monitor := health.NewMonitor(instanceName, reg)
-	healthEvents := monitor.Start(ctx)
+	healthEvents, err := inst.Events(runCtx)
+	if err != nil {
+		return 0, err
+	}
+	if interlock != nil {
+		healthEvents = inst.Interlock(runCtx, healthEvents, *interlock)
+	}
 	go func() {
 		for range healthEvents {
 			// events are already logged by the monitor; kernel integration in Phase 5
 		}
 	}()
 
-	containers, err := logging.GetContainerInfo(instanceName, composePath)
+	doneChan, errCh, err := inst.Logs(runCtx, true)
 	if err != nil {
-		return fmt.Errorf("getting container info: %w", err)
+		return 0, err
 	}
 
-	doneChan, errCh := logging.TailLogs(containers, shutdownChan, true)
+	var executorsDone <-chan map[string]int
+	if foreground.exitOnDone {
+		if len(inst.Services("executors")) == 0 {
+			logging.Warnf("%s has no executors to wait for; --exit-on-executors-done has no effect", logging.BoldMagenta(inst.Name))
+		} else {
+			executorsDone = waitExecutors(runCtx, inst)
+		}
+	}
 
 	select {
-	case <-shutdownChan:
-		fmt.Printf("\n%s\n", logging.Warning(fmt.Sprintf("Interrupt received — shutting down %s...", logging.BoldMagenta(instanceName))))
+	case <-runCtx.Done():
+		if stages.ctx.Err() == nil {
+			logging.Failuref("%s still running after %s — shutting down...", logging.BoldMagenta(inst.Name), foreground.timeout)
+			return timeoutExitCode, nil
+		}
+		logging.Warnf("Interrupt received — shutting down %s...", logging.BoldMagenta(inst.Name))
+	case codes := <-executorsDone:
+		logging.Infof("All executors exited — shutting down...")
+		return executorExitCode(inst, codes), nil
 	case <-doneChan:
-		fmt.Println(logging.Info("All log tails completed — shutting down..."))
+		logging.Infof("All log tails completed — shutting down...")
+		// every container has exited, so the executors' exit codes are final; they must be read before the shutdown removes the containers
+		statuses, err := inst.Status(context.Background())
+		if err != nil {
+			logging.Warnf("Reading executor exit codes: %v", err)
+			return 0, nil
+		}
+		codes := make(map[string]int)
+		for _, st := range statuses {
+			if st.Profile == "executors" && st.ContainerID != "" {
+				codes[st.Service] = st.ExitCode
+			}
+		}
+		return executorExitCode(inst, codes), nil
 	case err := <-errCh:
-		fmt.Println(logging.Failure(fmt.Sprintf("Log streaming error: %v", err)))
+		logging.Failuref("Log streaming error: %v", err)
 	}
 
-	return nil
+	return 0, nil
 }
 
-func extractProfileNames(profiles map[string][]string) []string {
-	var names []string
-	for k := range profiles {
-		names = append(names, k)
-	}
-	return names
-}
-
-func writeComposeToDisk(path string, data compose.RawCompose) error {
-	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
-		return fmt.Errorf("creating output dir: %w", err)
-	}
-	return compose.SaveRawYAML(path, data)
-}
-
-func writeInstanceMetadata(instanceName, path, lib, handle, group string, detached bool) error {
-	meta := util.InstanceMetadata{
-		Name:        instanceName,
-		ComposeFile: path,
-		CreatedAt:   time.Now().Format(time.RFC3339),
-		LibPath:     lib,
-		Handle:      handle,
-		Group:       group,
-		Detached:    detached,
-	}
-	home, _ := os.UserHomeDir()
-	storeDir := filepath.Join(home, ".coral_cli", "instances")
-	os.MkdirAll(storeDir, 0755)
-	data, _ := json.MarshalIndent(meta, "", "  ")
-	return os.WriteFile(filepath.Join(storeDir, instanceName+".json"), data, 0644)
+// delivers the executors' exit codes once every executor has exited; nothing is delivered if ctx ends first or they cannot be waited on
+func waitExecutors(ctx context.Context, inst *coral.Instance) <-chan map[string]int {
+	done := make(chan map[string]int, 1)
+	go func() {
+		codes, err := inst.WaitExecutors(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				logging.Warnf("Waiting for executors: %v", err)
+			}
+			return
+		}
+		done <- codes
+	}()
+	return done
 }
 
-func orderedProfiles(input []string) []string {
-	seen := make(map[string]bool)
-	var result []string
-	for _, key := range []string{"drivers", "skillsets", "executors"} {
-		for _, profile := range input {
-			if profile == key && !seen[profile] {
-				result = append(result, profile)
-				seen[profile] = true
-			}
+// reports each executor's exit code and returns the first non-zero one in service order, like docker compose --exit-code-from
+func executorExitCode(inst *coral.Instance, codes map[string]int) int {
+	services := inst.Services("executors")
+	slices.Sort(services)
+	first := 0
+	for _, svc := range services {
+		code, ok := codes[svc]
+		if !ok {
+			continue
+		}
+		if code == 0 {
+			logging.Successf("Executor %s exited 0", logging.BoldMagenta(svc))
+			continue
+		}
+		logging.Failuref("Executor %s exited %d", logging.BoldMagenta(svc), code)
+		if first == 0 {
+			first = code
 		}
 	}
-	return result
+	return first
 }
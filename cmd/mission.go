@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"coral_cli/internal/logging"
+	"coral_cli/pkg/coral"
+)
+
+var (
+	missionName             string
+	missionHandle           string
+	missionComposePath      string
+	missionEnvFile          string
+	missionStopOnFailure    bool
+	missionTimeout          time.Duration
+	missionNoFollow         bool
+	missionSkipVersionCheck bool
+)
+
+func init() {
+	missionRunCmd.Args = cobra.MinimumNArgs(1)
+
+	missionRunCmd.Flags().StringVarP(&missionName, "name", "n", "", "Name of the running instance to run the executors in")
+	missionRunCmd.Flags().StringVar(&missionHandle, "handle", "", "Handle of the running instance to run the executors in")
+	missionRunCmd.Flags().StringVarP(&missionComposePath, "compose-file", "f", "", "Optional Docker Compose .yaml file with executors to add to the instance (without starting them) before the mission runs")
+	missionRunCmd.Flags().StringVar(&missionEnvFile, "env-file", "", "Optional path to .env file to use for compose substitutions")
+	missionRunCmd.Flags().BoolVar(&missionStopOnFailure, "stop-on-failure", false, "Skip the remaining executors once one exits non-zero, times out or fails to start")
+	missionRunCmd.Flags().DurationVar(&missionTimeout, "timeout", 0, "How long each executor may run before it is killed and counted as failed (e.g. 10m); unbounded when 0")
+	missionRunCmd.Flags().BoolVar(&missionNoFollow, "no-follow", false, "Do not stream executor logs while they run")
+	missionRunCmd.Flags().BoolVar(&missionSkipVersionCheck, "skip-version-check", false, "Skip coral.version compatibility check between CLI and images")
+
+	missionRunCmd.RegisterFlagCompletionFunc("compose-file", completeComposeFiles)
+	missionRunCmd.RegisterFlagCompletionFunc("name", completeInstanceNames)
+	missionRunCmd.RegisterFlagCompletionFunc("handle", completeInstanceHandles)
+
+	missionCmd.AddCommand(missionRunCmd)
+}
+
+var missionCmd = &cobra.Command{
+	Use:   "mission",
+	Short: "Runs executors against the drivers and skillsets of a running Coral instance",
+}
+
+var missionRunCmd = &cobra.Command{
+	Use:   "run EXECUTOR...",
+	Short: "Runs executors of a running Coral instance one after another, each until it exits",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if missionTimeout < 0 {
+			return &coral.Error{Code: coral.ErrCodeInvalidInput, Err: fmt.Errorf("invalid --timeout %s: must not be negative", missionTimeout)}
+		}
+		result, err := runMission(cmd.Context(), args, coral.MissionOptions{
+			ComposePath:      missionComposePath,
+			EnvFile:          missionEnvFile,
+			SkipVersionCheck: missionSkipVersionCheck,
+			StopOnFailure:    missionStopOnFailure,
+			Timeout:          missionTimeout,
+			Follow:           !missionNoFollow,
+			Progress:         printMissionStep,
+		})
+		if result == nil {
+			return err
+		}
+		if perr := printResult(result); perr != nil {
+			return perr
+		}
+		if err != nil {
+			// the outcome of each executor has been reported; coral exits like the first one that failed
+			return exitWith(cmd, missionExitCode(result, err))
+		}
+		return nil
+	},
+}
+
+func runMission(ctx context.Context, executors []string, opts coral.MissionOptions) (*coral.MissionResult, error) {
+	if (missionName == "") == (missionHandle == "") {
+		return nil, &coral.Error{Code: coral.ErrCodeInvalidInput, Err: fmt.Errorf("exactly one of --name or --handle is required")}
+	}
+	inst, err := openByNameOrHandle(ctx, missionName, missionHandle)
+	if err != nil {
+		return nil, err
+	}
+	if inst == nil {
+		return nil, &coral.Error{Code: coral.ErrCodeNotFound, Err: fmt.Errorf("no instance found with handle %s", missionHandle)}
+	}
+
+	// ctrl+c stops the running executor and skips the rest, leaving the instance up
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	result, err := coral.NewLauncher(Version).Mission(ctx, inst, executors, opts)
+	if result != nil {
+		passed := 0
+		for _, step := range result.Steps {
+			if step.Succeeded() {
+				passed++
+			}
+		}
+		if err == nil {
+			logging.Successf("Mission complete: %d/%d executors succeeded", passed, len(result.Steps))
+		} else {
+			logging.Failuref("Mission failed: %d/%d executors succeeded", passed, len(result.Steps))
+		}
+	}
+	return result, err
+}
+
+func printMissionStep(step coral.MissionStep) {
+	duration := time.Duration(step.DurationSeconds * float64(time.Second)).Round(time.Millisecond)
+	switch {
+	case step.Skipped:
+		logging.Warnf("Skipped executor %s", logging.BoldMagenta(step.Executor))
+	case step.Succeeded():
+		logging.Successf("Executor %s exited 0 after %s", logging.BoldMagenta(step.Executor), duration)
+	case step.Error == "":
+		logging.Failuref("Executor %s exited %d after %s", logging.BoldMagenta(step.Executor), step.ExitCode, duration)
+	}
+}
+
+// the exit code of the first executor that exited non-zero, or 1 when none did (e.g. one timed out or failed to start); 130 when interrupted
+func missionExitCode(result *coral.MissionResult, err error) int {
+	if coral.CodeOf(err) == coral.ErrCodeInterrupted {
+		return 130
+	}
+	for _, step := range result.Steps {
+		if step.ExitCode > 0 {
+			return step.ExitCode
+		}
+	}
+	return 1
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"coral_cli/internal/logging"
+	"coral_cli/internal/output"
+	"coral_cli/pkg/coral"
+)
+
+var (
+	outputFlag   string
+	outputFormat = output.Text
+)
+
+var (
+	verboseFlag   bool
+	quietFlag     bool
+	logFormatFlag string
+	noColorFlag   bool
+)
+
+// parses --output and, for structured formats, moves human-readable logging to stderr so stdout carries only the result document
+func setupOutput(format string) error {
+	f, err := output.ParseFormat(format)
+	if err != nil {
+		return err
+	}
+	outputFormat = f
+	if f.Structured() {
+		logging.Output = os.Stderr
+	}
+	return nil
+}
+
+// applies --verbose/--quiet/--log-format/--no-color to the logger; --verbose also traces every docker command coral runs along with its duration
+func setupLogging(verbose, quiet bool, format string, noColor bool) error {
+	if verbose && quiet {
+		return &coral.Error{Code: coral.ErrCodeInvalidInput, Err: fmt.Errorf("--verbose and --quiet are mutually exclusive")}
+	}
+	level := logging.LevelInfo
+	if verbose {
+		level = logging.LevelDebug
+	} else if quiet {
+		level = logging.LevelWarn
+	}
+	if err := logging.Configure(level, format, noColor); err != nil {
+		return &coral.Error{Code: coral.ErrCodeInvalidInput, Err: err}
+	}
+	return nil
+}
+
+// writes v to stdout as the command's structured result; does nothing in text mode, where commands print their own human-readable summary
+func printResult(v any) error {
+	if !outputFormat.Structured() {
+		return nil
+	}
+	return output.Write(os.Stdout, outputFormat, v)
+}
+
+// writes err to stdout as a structured error result; does nothing in text mode
+func printError(err error) {
+	if !outputFormat.Structured() || err == nil {
+		return
+	}
+	_ = output.Write(os.Stdout, outputFormat, output.ErrorResult{Error: errorDetail(err)})
+}
+
+func errorDetail(err error) output.ErrorDetail {
+	return output.ErrorDetail{Code: string(coral.CodeOf(err)), Message: err.Error()}
+}
+
+// removes -o/--output from arguments that are otherwise passed straight through to docker, returning the remaining arguments and the requested format
+func extractOutputFlag(args []string) ([]string, string) {
+	var rest []string
+	format := ""
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "-o" || arg == "--output":
+			if i+1 < len(args) {
+				format = args[i+1]
+				i++
+			}
+		case strings.HasPrefix(arg, "--output="):
+			format = strings.TrimPrefix(arg, "--output=")
+		case strings.HasPrefix(arg, "-o") && len(arg) > 2 && !strings.HasPrefix(arg, "--"):
+			format = arg[2:]
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return rest, format
+}
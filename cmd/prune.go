@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	"coral_cli/internal/logging"
+	"coral_cli/pkg/coral"
+)
+
+func init() {
+	pruneCmd.Args = cobra.NoArgs
+}
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Kills and removes instances whose shutdown was abandoned",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return prune(cmd.Context())
+	},
+}
+
+// structured result of a prune; one entry per instance removed
+type pruneResult struct {
+	Instances []shutdownEntry `json:"instances"`
+}
+
+func prune(ctx context.Context) error {
+	pruned, err := coral.Prune(ctx)
+	result := pruneResult{Instances: []shutdownEntry{}}
+	for _, meta := range pruned {
+		result.Instances = append(result.Instances, shutdownEntry{Name: meta.Name, Handle: meta.Handle, Group: meta.Group})
+	}
+	if err != nil {
+		return err
+	}
+	if len(pruned) == 0 {
+		logging.Infof("No abandoned instances found.")
+	} else {
+		logging.Successf("Done")
+	}
+	return printResult(result)
+}
@@ -2,31 +2,43 @@ package cmd
 
 import (
 	"bufio"
+	"context"
 	"fmt"
-	"os/exec"
+	"os"
 	"strings"
 
 	"github.com/spf13/cobra"
+
+	"coral_cli/internal/docker"
 )
 
 var psCmd = &cobra.Command{
 	Use:   "ps",
 	Short: "List only running containers from coral images",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return showCoralContainers(args)
+		return showCoralContainers(cmd.Context(), args)
 	},
 }
 
-func showCoralContainers(args []string) error {
+func showCoralContainers(ctx context.Context, args []string) error {
+	if outputFormat.Structured() {
+		rows, err := coralRowsJSON(ctx, "ps", args, "Image")
+		if err != nil {
+			return err
+		}
+		return printResult(rows)
+	}
+
 	allArgs := append([]string{"ps"}, args...)
-	cmd := exec.Command("docker", allArgs...)
+	cmd := docker.CommandContext(ctx, docker.Query, allArgs...)
+	cmd.Stderr = os.Stderr
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		panic(err)
+		return err
 	}
 
 	if err := cmd.Start(); err != nil {
-		panic(err)
+		return fmt.Errorf("running docker ps: %w", err)
 	}
 
 	scanner := bufio.NewScanner(stdout)
@@ -52,6 +64,8 @@ func showCoralContainers(args []string) error {
 		}
 	}
 
-	cmd.Wait()
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("docker ps failed: %w", err)
+	}
 	return nil
 }
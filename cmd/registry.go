@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"coral_cli/internal/registry"
+)
+
+var (
+	registryLibDir string
+)
+
+func init() {
+	registryCmd.Args = cobra.NoArgs
+
+	registryCmd.Flags().StringVar(&registryLibDir, "lib-dir", "", "Override CORAL_LIB path (takes precedence over $CORAL_LIB environment variable)")
+}
+
+var registryCmd = &cobra.Command{
+	Use:   "registry",
+	Short: "Lists the payload extractions and executor injections recorded in the library registry",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return showRegistry(registryLibDir)
+	},
+}
+
+// structured contents of a registry
+type registryResult struct {
+	LibDir      string                      `json:"lib_dir"`
+	Extractions []registry.ExtractionRecord `json:"extractions"`
+	Injections  []registry.InjectionRecord  `json:"injections"`
+}
+
+func showRegistry(libDirOverride string) error {
+	libDir := libDirOverride
+	if libDir == "" {
+		libDir = os.Getenv("CORAL_LIB")
+	}
+	if strings.TrimSpace(libDir) == "" {
+		abs, err := filepath.Abs("./lib")
+		if err != nil {
+			return fmt.Errorf("resolving ./lib: %w", err)
+		}
+		libDir = abs
+	}
+
+	reg, err := registry.Load(libDir)
+	if err != nil {
+		return fmt.Errorf("loading registry: %w", err)
+	}
+
+	result := registryResult{LibDir: libDir, Extractions: []registry.ExtractionRecord{}, Injections: []registry.InjectionRecord{}}
+	for _, rec := range reg.AllExtractions() {
+		result.Extractions = append(result.Extractions, rec)
+	}
+	sort.Slice(result.Extractions, func(i, j int) bool { return result.Extractions[i].PayloadID < result.Extractions[j].PayloadID })
+	for _, rec := range reg.AllInjections() {
+		result.Injections = append(result.Injections, rec)
+	}
+	sort.Slice(result.Injections, func(i, j int) bool { return result.Injections[i].ContainerID < result.Injections[j].ContainerID })
+
+	if outputFormat.Structured() {
+		return printResult(result)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PAYLOAD\tBTCPP\tROS\tPRIORITY\tINSTANCES")
+	for _, rec := range result.Extractions {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", rec.PayloadID, rec.BtcppVersion, rec.RosDistro, rec.Priority, strings.Join(rec.InstanceIDs, ","))
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "CONTAINER\tINSTANCE\tSCOPE\tLIBRARIES\tSHADOWED\tREJECTED")
+	for _, rec := range result.Injections {
+		shadowed, rejected := 0, 0
+		for _, l := range rec.Libs {
+			switch {
+			case l.Rejected != "":
+				rejected++
+			case l.Shadowed:
+				shadowed++
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%d\n", shortContainerID(rec.ContainerID), rec.InstanceID, rec.Scope, len(rec.Libs)-shadowed-rejected, shadowed, rejected)
+	}
+	return w.Flush()
+}
+
+func shortContainerID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"coral_cli/internal/logging"
+	"coral_cli/pkg/coral"
+)
+
+var resumeSelector selectorFlags
+
+func init() {
+	resumeCmd.Args = cobra.NoArgs
+
+	addSelectorFlags(resumeCmd, &resumeSelector, "resume")
+	addDryRunFlag(resumeCmd, &resumeSelector)
+}
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume",
+	Short: "Resumes instances halted by coral estop",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return resume(cmd, &resumeSelector)
+	},
+}
+
+// resumes every selected instance that is emergency-stopped, all of them when no criteria are given
+func resume(cmd *cobra.Command, sel *selectorFlags) error {
+	ctx := cmd.Context()
+	selector, err := sel.selector()
+	if err != nil {
+		return err
+	}
+	if sel.dryRun {
+		instances, err := sel.selectInstances(ctx, false)
+		if err != nil {
+			return err
+		}
+		var stopped []*coral.Instance
+		for _, inst := range instances {
+			if inst.EStop != nil {
+				stopped = append(stopped, inst)
+			}
+		}
+		return printDryRun("resume", stopped)
+	}
+
+	event, err := coral.Resume(ctx, coral.EStopOptions{Selector: selector})
+	if event != nil {
+		for _, t := range event.Instances {
+			if n := countContainers(t.Containers); n > 0 {
+				logging.Successf("Resumed %d container(s) of %s", n, logging.BoldMagenta(t.Instance))
+			}
+		}
+		if len(event.Instances) == 0 {
+			logging.Infof("No emergency-stopped instances found.")
+		}
+		if perr := printResult(event); perr != nil {
+			return perr
+		}
+	}
+	if err != nil && event != nil {
+		// the event, with the error of every container that could not be resumed, has been printed above
+		if !outputFormat.Structured() {
+			logging.Failuref("%s", err)
+		}
+		return exitWith(cmd, 1)
+	}
+	return err
+}
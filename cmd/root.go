@@ -1,11 +1,14 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 
 	"github.com/spf13/cobra"
+
+	"coral_cli/internal/docker"
 )
 
 var rootCmd = &cobra.Command{
@@ -25,10 +28,23 @@ var rootCmd = &cobra.Command{
 		switch args[0] {
 		case "-v", "--version":
 			fmt.Printf("Coral version %s\n", Version)
-		case "images":
-			err = imagesCmd.RunE(cmd, args[1:])
-		case "ps":
-			err = psCmd.RunE(cmd, args[1:])
+		case "images", "ps":
+			// --output is coral's own flag, so pull it out before the rest is handed to docker
+			rest, format := extractOutputFlag(args[1:])
+			if err = setupOutput(format); err == nil {
+				err = setupLogging(false, false, "text", false)
+			}
+			if err == nil {
+				if args[0] == "images" {
+					err = imagesCmd.RunE(cmd, rest)
+				} else {
+					err = psCmd.RunE(cmd, rest)
+				}
+			}
+			if err != nil && !outputFormat.Structured() {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			}
+			printError(err)
 		default:
 			err = runDockerCommand(args...)
 		}
@@ -37,14 +53,48 @@ var rootCmd = &cobra.Command{
 			os.Exit(1)
 		}
 	},
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := setupOutput(outputFlag); err != nil {
+			return err
+		}
+		if err := setupLogging(verboseFlag, quietFlag, logFormatFlag, noColorFlag); err != nil {
+			return err
+		}
+		docker.SetTimeouts(dockerTimeouts)
+		return nil
+	},
 }
 
+// bounds on the docker commands coral runs itself, set from the --*-timeout flags
+var dockerTimeouts = docker.DefaultTimeouts
+
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
+		var exit *exitError
+		if errors.As(err, &exit) {
+			os.Exit(exit.code)
+		}
+		printError(err)
 		os.Exit(1)
 	}
 }
 
+// ends coral with the given exit status once the command has already reported the outcome itself (e.g. the exit codes of a mission's executors)
+type exitError struct {
+	code int
+}
+
+func (e *exitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+// returns an exitError for cmd, keeping cobra from printing it or the usage
+func exitWith(cmd *cobra.Command, code int) error {
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+	return &exitError{code: code}
+}
+
 func runDockerCommand(args ...string) error {
 	dockerCmd := exec.Command("docker", args...)
 	dockerCmd.Stdin = os.Stdin
@@ -60,11 +110,31 @@ func runDockerCommand(args ...string) error {
 }
 
 func init() {
+	rootCmd.PersistentFlags().StringVarP(&outputFlag, "output", "o", "text", "Output format for command results (text, json, yaml); human-readable logs go to stderr for json and yaml")
+	rootCmd.PersistentFlags().BoolVar(&verboseFlag, "verbose", false, "Log debug detail, including every docker command run and how long it took")
+	rootCmd.PersistentFlags().BoolVarP(&quietFlag, "quiet", "q", false, "Only log warnings and failures")
+	rootCmd.PersistentFlags().StringVar(&logFormatFlag, "log-format", "text", "Format of log records (text, json)")
+	rootCmd.PersistentFlags().DurationVar(&dockerTimeouts.Query, "query-timeout", docker.DefaultTimeouts.Query, "Timeout for docker inspect and ps lookups (0 disables)")
+	rootCmd.PersistentFlags().DurationVar(&dockerTimeouts.Copy, "copy-timeout", docker.DefaultTimeouts.Copy, "Timeout for copying libraries out of images and into executors (0 disables)")
+	rootCmd.PersistentFlags().DurationVar(&dockerTimeouts.Pull, "pull-timeout", docker.DefaultTimeouts.Pull, "Timeout for pulling a missing image (0 disables)")
+	rootCmd.PersistentFlags().DurationVar(&dockerTimeouts.Compose, "compose-timeout", docker.DefaultTimeouts.Compose, "Timeout for each docker compose create, up, start, kill and down (0 disables)")
+	rootCmd.PersistentFlags().BoolVar(&noColorFlag, "no-color", false, "Disable coloured output (also disabled when NO_COLOR is set)")
+
 	// commands that do not overload docker commands belong here
+	rootCmd.AddCommand(addCmd)
+	rootCmd.AddCommand(behaviorsCmd)
+	rootCmd.AddCommand(btCmd)
 	rootCmd.AddCommand(completionCmd)
+	rootCmd.AddCommand(estopCmd)
 	rootCmd.AddCommand(launchCmd)
+	rootCmd.AddCommand(missionCmd)
+	rootCmd.AddCommand(pruneCmd)
+	rootCmd.AddCommand(registryCmd)
+	rootCmd.AddCommand(resumeCmd)
 	rootCmd.AddCommand(shutdownCmd)
+	rootCmd.AddCommand(statusCmd)
 	rootCmd.AddCommand(tailCmd)
+	rootCmd.AddCommand(upCmd)
 	rootCmd.AddCommand(verifyCmd)
 	rootCmd.AddCommand(versionCmd)
 }
@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"coral_cli/internal/logging"
+	"coral_cli/internal/util"
+	"coral_cli/pkg/coral"
+)
+
+// instance selection flags shared by every command that acts on existing instances; criteria are combined with AND, repeated values of one criterion with OR
+type selectorFlags struct {
+	names     []string
+	handles   []string
+	groups    []string
+	labels    []string
+	olderThan time.Duration
+	all       bool
+	dryRun    bool
+}
+
+// registers -n, --handle, -g, -l, --older-than and -a on cmd; verb completes the flag descriptions (e.g. "shut down")
+func addSelectorFlags(cmd *cobra.Command, f *selectorFlags, verb string) {
+	cmd.Flags().StringSliceVarP(&f.names, "name", "n", []string{}, fmt.Sprintf("Names of instances to %s; glob patterns such as robot-* are accepted", verb))
+	cmd.Flags().StringSliceVar(&f.handles, "handle", []string{}, fmt.Sprintf("Handles of instances to %s; glob patterns are accepted", verb))
+	cmd.Flags().StringSliceVarP(&f.groups, "group", "g", []string{}, fmt.Sprintf("Groups of instances to %s; glob patterns are accepted", verb))
+	cmd.Flags().StringSliceVarP(&f.labels, "label", "l", []string{}, "Only instances with this key=value label, given with coral launch --label or set on one of their containers; repeat to require several")
+	cmd.Flags().DurationVar(&f.olderThan, "older-than", 0, "Only instances launched at least this long ago (e.g. 2h)")
+	cmd.Flags().BoolVarP(&f.all, "all", "a", false, fmt.Sprintf("%s every instance", strings.ToUpper(verb[:1])+verb[1:]))
+
+	cmd.RegisterFlagCompletionFunc("name", completeInstanceNames)
+	cmd.RegisterFlagCompletionFunc("handle", completeInstanceHandles)
+	cmd.RegisterFlagCompletionFunc("group", completeInstanceGroups)
+}
+
+// registers --dry-run for commands that change the instances they select
+func addDryRunFlag(cmd *cobra.Command, f *selectorFlags) {
+	cmd.Flags().BoolVar(&f.dryRun, "dry-run", false, "List the instances that would be affected without changing them")
+}
+
+// reports whether any selection criterion (or --all) was given
+func (f *selectorFlags) given() bool {
+	return f.all || len(f.names) > 0 || len(f.handles) > 0 || len(f.groups) > 0 || len(f.labels) > 0 || f.olderThan > 0
+}
+
+func (f *selectorFlags) selector() (coral.Selector, error) {
+	labels, err := parseLabels(f.labels)
+	if err != nil {
+		return coral.Selector{}, err
+	}
+	sel := coral.Selector{Names: f.names, Handles: f.handles, Groups: f.groups, Labels: labels, OlderThan: f.olderThan}
+	if f.olderThan < 0 {
+		return sel, &coral.Error{Code: coral.ErrCodeInvalidInput, Err: fmt.Errorf("invalid --older-than %s: must not be negative", f.olderThan)}
+	}
+	return sel, sel.Validate()
+}
+
+// parses repeated --label key=value flags; nil when none are given
+func parseLabels(values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	labels := make(map[string]string)
+	for _, label := range values {
+		key, value, ok := strings.Cut(label, "=")
+		if !ok || key == "" {
+			return nil, &coral.Error{Code: coral.ErrCodeInvalidInput, Err: fmt.Errorf("invalid --label %q: expected key=value", label)}
+		}
+		labels[key] = value
+	}
+	return labels, nil
+}
+
+// resolves the flags to instances; with required set, some criterion (or --all) must be given, and criteria that match nothing are reported as not found
+func (f *selectorFlags) selectInstances(ctx context.Context, required bool) ([]*coral.Instance, error) {
+	if required && !f.given() {
+		return nil, &coral.Error{Code: coral.ErrCodeInvalidInput, Err: fmt.Errorf("no instances selected: use --name, --handle, --group, --label, --older-than or --all")}
+	}
+	sel, err := f.selector()
+	if err != nil {
+		return nil, err
+	}
+	instances, err := coral.Select(ctx, sel)
+	if err != nil {
+		return nil, err
+	}
+	if len(instances) == 0 && !sel.Empty() {
+		return nil, &coral.Error{Code: coral.ErrCodeNotFound, Err: fmt.Errorf("no instances found matching criteria")}
+	}
+	return instances, nil
+}
+
+// opens the instance with the given name, or else the one whose handle is exactly handle (not a glob pattern); nil when no instance has the handle
+func openByNameOrHandle(ctx context.Context, name, handle string) (*coral.Instance, error) {
+	if name != "" {
+		return coral.Open(name)
+	}
+	instances, err := coral.Select(ctx, coral.Selector{Handles: []string{handle}})
+	if err != nil {
+		return nil, err
+	}
+	for _, inst := range instances {
+		if inst.Handle == handle {
+			return inst, nil
+		}
+	}
+	return nil, nil
+}
+
+// structured result of --dry-run: the instances the command would have acted on
+type dryRunResult struct {
+	DryRun    bool            `json:"dry_run"`
+	Instances []shutdownEntry `json:"instances"`
+}
+
+// lists the instances a command would act on, as text or as a structured result
+func printDryRun(verb string, instances []*coral.Instance) error {
+	result := dryRunResult{DryRun: true, Instances: []shutdownEntry{}}
+	for _, inst := range instances {
+		logging.Infof("Would %s %s", verb, describeInstance(inst))
+		result.Instances = append(result.Instances, shutdownEntry{Name: inst.Name, Handle: inst.Handle, Group: inst.Group})
+	}
+	if len(instances) == 0 {
+		logging.Infof("No instances found.")
+	}
+	return printResult(result)
+}
+
+// the instance's name, followed by its handle and group when set
+func describeInstance(inst *coral.Instance) string {
+	desc := logging.BoldMagenta(inst.Name)
+	if inst.Handle != "" {
+		desc += fmt.Sprintf(" (handle %s)", logging.BoldMagenta(inst.Handle))
+	}
+	if inst.Group != "" {
+		desc += fmt.Sprintf(" [group %s]", inst.Group)
+	}
+	return desc
+}
+
+func completeInstanceNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return completeMetadata(toComplete, func(m util.InstanceMetadata) string { return m.Name })
+}
+
+func completeInstanceHandles(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return completeMetadata(toComplete, func(m util.InstanceMetadata) string { return m.Handle })
+}
+
+func completeInstanceGroups(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return completeMetadata(toComplete, func(m util.InstanceMetadata) string { return m.Group })
+}
+
+func completeMetadata(toComplete string, field func(util.InstanceMetadata) string) ([]string, cobra.ShellCompDirective) {
+	metadataList, err := util.LoadAllMetadata()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	var suggestions []string
+	for _, m := range metadataList {
+		if v := field(m); v != "" && strings.HasPrefix(v, toComplete) {
+			suggestions = append(suggestions, v)
+		}
+	}
+	return suggestions, cobra.ShellCompDirectiveNoFileComp
+}
@@ -1,34 +1,36 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"math"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 
-	"coral_cli/internal/cleanup"
-	"coral_cli/internal/compose"
 	"coral_cli/internal/logging"
-	"coral_cli/internal/util"
+	"coral_cli/internal/output"
+	"coral_cli/pkg/coral"
 )
 
 var (
-	shutdownName   string
-	shutdownHandle string
-	shutdownGroup  string
-	shutdownAll    bool
-	shutdownKill   bool
+	shutdownSelector    selectorFlags
+	shutdownKill        bool
+	shutdownStopTimeout []string
+	shutdownStopSignal  string
 )
 
 func init() {
 	shutdownCmd.Args = cobra.NoArgs
 
-	shutdownCmd.Flags().StringVarP(&shutdownName, "name", "n", "", "Name of instance to shut down")
-	shutdownCmd.Flags().StringVarP(&shutdownGroup, "group", "g", "", "Group to shut down")
-	shutdownCmd.Flags().StringVar(&shutdownHandle, "handle", "", "Handle to shut down")
-	shutdownCmd.Flags().BoolVarP(&shutdownAll, "all", "a", false, "Shut down all instances")
-	shutdownCmd.Flags().BoolVar(&shutdownKill, "kill", true, "Forcefully kills instances before removing them")
+	addSelectorFlags(shutdownCmd, &shutdownSelector, "shut down")
+	addDryRunFlag(shutdownCmd, &shutdownSelector)
+	shutdownCmd.Flags().BoolVar(&shutdownKill, "kill", false, "Forcefully kills instances before removing them instead of stopping them gracefully")
+	shutdownCmd.Flags().StringSliceVar(&shutdownStopTimeout, "stop-timeout", []string{"10"}, "Seconds each profile is given to stop gracefully before it is killed, either for all profiles or as profile=seconds (e.g. executors=30)")
+	shutdownCmd.Flags().StringVar(&shutdownStopSignal, "stop-signal", "", "Signal sent to stop each container gracefully (e.g. SIGINT); defaults to each service's stop_signal")
 
 	shutdownCmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		if toComplete == "" {
@@ -44,223 +46,101 @@ func init() {
 		}
 		return nil, cobra.ShellCompDirectiveNoFileComp
 	}
-
-	shutdownCmd.RegisterFlagCompletionFunc("name", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		metadataList, err := util.LoadAllMetadata()
-		if err != nil {
-			return nil, cobra.ShellCompDirectiveError
-		}
-		var suggestions []string
-		for _, m := range metadataList {
-			if strings.HasPrefix(m.Name, toComplete) {
-				suggestions = append(suggestions, m.Name)
-			}
-		}
-		return suggestions, cobra.ShellCompDirectiveNoFileComp
-	})
-	shutdownCmd.RegisterFlagCompletionFunc("group", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		metadataList, err := util.LoadAllMetadata()
-		if err != nil {
-			return nil, cobra.ShellCompDirectiveError
-		}
-		var suggestions []string
-		for _, m := range metadataList {
-			if strings.HasPrefix(m.Group, toComplete) {
-				suggestions = append(suggestions, m.Group)
-			}
-		}
-		return suggestions, cobra.ShellCompDirectiveNoFileComp
-	})
-	shutdownCmd.RegisterFlagCompletionFunc("handle", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		metadataList, err := util.LoadAllMetadata()
-		if err != nil {
-			return nil, cobra.ShellCompDirectiveError
-		}
-		var suggestions []string
-		for _, m := range metadataList {
-			if strings.HasPrefix(m.Handle, toComplete) {
-				suggestions = append(suggestions, m.Handle)
-			}
-		}
-		return suggestions, cobra.ShellCompDirectiveNoFileComp
-	})
 }
 
 var shutdownCmd = &cobra.Command{
 	Use:   "shutdown",
 	Short: "Stops and cleans up Coral instances",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if shutdownAll {
-			return shutdownAllInstances(shutdownKill)
-		}
-		if shutdownName != "" {
-			return shutdownByName(shutdownName, shutdownKill)
-		}
-		if shutdownHandle != "" {
-			return shutdownByHandle(shutdownHandle, shutdownKill)
-		}
-		if shutdownGroup != "" {
-			return shutdownByGroup(shutdownGroup, shutdownKill)
+		opts, err := stopOptions(shutdownKill, shutdownStopTimeout, shutdownStopSignal)
+		if err != nil {
+			return err
 		}
-		return fmt.Errorf("no shutdown criteria provided: use --compose-file, --handle, --group, or --all")
+		opts.Progress = printStopProgress
+		return shutdown(cmd.Context(), &shutdownSelector, opts)
 	},
 }
 
-func shutdownAllInstances(kill bool) error {
-	metadataList, err := util.LoadAllMetadata()
-	if err != nil {
-		return fmt.Errorf("loading metadata: %w", err)
-	}
+// structured result of a shutdown; one entry per instance that was matched
+type shutdownResult struct {
+	Instances []shutdownEntry `json:"instances"`
+}
 
-	if len(metadataList) == 0 {
-		fmt.Println("No instances found.")
-		return nil
-	}
+type shutdownEntry struct {
+	Name   string              `json:"name"`
+	Handle string              `json:"handle,omitempty"`
+	Group  string              `json:"group,omitempty"`
+	Error  *output.ErrorDetail `json:"error,omitempty"`
+}
 
-	for _, meta := range metadataList {
-		fmt.Println(logging.Info(fmt.Sprintf("Shutting down %s...", logging.BoldMagenta(meta.Name))))
-		profiles, err := extractProfiles(meta.ComposeFile)
-		if err != nil {
-			fmt.Printf("Failed to extract profiles for %s: %v\n", meta.Name, err)
-			continue
-		}
-		if err := cleanup.StopCompose(meta.Name, meta.ComposeFile, kill, profiles); err != nil {
-			fmt.Printf("Failed to stop compose for %s: %v\n", meta.Name, err)
-		}
-		if meta.Detached {
-			if err := cleanup.RemoveInstanceFiles(meta.Name); err != nil {
-				fmt.Printf("Failed to remove files for %s: %v\n", meta.Name, err)
-			}
-		} else {
-			fmt.Println(logging.Warning(fmt.Sprintf("Instance %s is not detached; file removal will be left to the foreground process.", meta.Name)))
-		}
+// shuts a single instance down, reporting failures without aborting the surrounding batch
+func shutdownInstance(ctx context.Context, inst *coral.Instance, opts coral.ShutdownOptions) shutdownEntry {
+	entry := shutdownEntry{Name: inst.Name, Handle: inst.Handle, Group: inst.Group}
+	if err := inst.Shutdown(ctx, opts); err != nil {
+		logging.Failuref("Failed to shut down %s: %v", inst.Name, err)
+		detail := errorDetail(err)
+		entry.Error = &detail
 	}
-
-	fmt.Println(logging.Success("Done"))
-	return nil
+	return entry
 }
 
-func shutdownByName(name string, kill bool) error {
-	metadataList, err := util.LoadAllMetadata()
+// shuts down every instance selected by sel, one after another, continuing past failures
+func shutdown(ctx context.Context, sel *selectorFlags, opts coral.ShutdownOptions) error {
+	instances, err := sel.selectInstances(ctx, true)
 	if err != nil {
 		return err
 	}
-
-	for _, meta := range metadataList {
-		if meta.Name == name {
-			fmt.Println(logging.Info(fmt.Sprintf("Shutting down %s...", logging.BoldMagenta(meta.Name))))
-			profiles, err := extractProfiles(meta.ComposeFile)
-			if err != nil {
-				fmt.Printf("Failed to extract profiles for %s: %v\n", meta.Name, err)
-				continue
-			}
-			if err := cleanup.StopCompose(meta.Name, meta.ComposeFile, kill, profiles); err != nil {
-				fmt.Printf("Failed to stop compose for %s: %v\n", meta.Name, err)
-			}
-			if meta.Detached {
-				if err := cleanup.RemoveInstanceFiles(meta.Name); err != nil {
-					fmt.Printf("Failed to remove files for %s: %v\n", meta.Name, err)
-				}
-			} else {
-				fmt.Println(logging.Warning(fmt.Sprintf("Instance %s is not detached; file removal will be left to the foreground process.", meta.Name)))
-			}
-			fmt.Println(logging.Success("Done"))
-			return nil
-		}
+	if sel.dryRun {
+		return printDryRun("shut down", instances)
 	}
-	return fmt.Errorf("no instance found with name: %s", name)
-}
 
-func shutdownByHandle(handle string, kill bool) error {
-	metadataList, err := util.LoadAllMetadata()
-	if err != nil {
-		return err
+	result := shutdownResult{Instances: []shutdownEntry{}}
+	if len(instances) == 0 {
+		logging.Infof("No instances found.")
+		return printResult(result)
 	}
-
-	for _, meta := range metadataList {
-		if meta.Handle == handle {
-			fmt.Println(logging.Info(fmt.Sprintf("Shutting down %s with handle %s...", logging.BoldMagenta(meta.Name), logging.BoldMagenta(meta.Handle))))
-			profiles, err := extractProfiles(meta.ComposeFile)
-			if err != nil {
-				fmt.Printf("Failed to extract profiles for %s: %v\n", meta.Name, err)
-				continue
-			}
-			if err := cleanup.StopCompose(meta.Name, meta.ComposeFile, kill, profiles); err != nil {
-				fmt.Printf("Failed to stop compose for %s: %v\n", meta.Name, err)
-			}
-			if meta.Detached {
-				if err := cleanup.RemoveInstanceFiles(meta.Name); err != nil {
-					fmt.Printf("Failed to remove files for %s: %v\n", meta.Name, err)
-				}
-			} else {
-				fmt.Println(logging.Warning(fmt.Sprintf("Instance %s is not detached; file removal will be left to the foreground process.", meta.Name)))
-			}
-			fmt.Println(logging.Success("Done"))
-			return nil
-		}
+	for _, inst := range instances {
+		logging.Infof("Shutting down %s...", describeInstance(inst))
+		result.Instances = append(result.Instances, shutdownInstance(ctx, inst, opts))
 	}
-	return fmt.Errorf("no instance found with handle: %s", handle)
+	logging.Successf("Done")
+	return printResult(result)
 }
 
-func shutdownByGroup(group string, kill bool) error {
-	metadataList, err := util.LoadAllMetadata()
-	if err != nil {
-		return err
-	}
-
-	found := false
-	for _, meta := range metadataList {
-		if meta.Group == group {
-			found = true
-			fmt.Println(logging.Info(fmt.Sprintf("Shutting down %s with group %s...", logging.BoldMagenta(meta.Name), logging.BoldMagenta(meta.Group))))
-			profiles, err := extractProfiles(meta.ComposeFile)
-			if err != nil {
-				fmt.Printf("Failed to extract profiles for %s: %v\n", meta.Name, err)
-				continue
-			}
-			if err := cleanup.StopCompose(meta.Name, meta.ComposeFile, kill, profiles); err != nil {
-				fmt.Printf("Failed to stop compose for %s: %v\n", meta.Name, err)
-			}
-			if meta.Detached {
-				if err := cleanup.RemoveInstanceFiles(meta.Name); err != nil {
-					fmt.Printf("Failed to remove files for %s: %v\n", meta.Name, err)
-				}
-			} else {
-				fmt.Println(logging.Warning(fmt.Sprintf("Instance %s is not detached; file removal will be left to the foreground process.", meta.Name)))
-			}
+// builds shutdown options from --kill, --stop-timeout and --stop-signal; each --stop-timeout value is either plain seconds for every profile or profile=seconds for one
+func stopOptions(kill bool, timeouts []string, signal string) (coral.ShutdownOptions, error) {
+	opts := coral.ShutdownOptions{Kill: kill, Signal: signal, ProfileTimeouts: map[string]time.Duration{}}
+	for _, value := range timeouts {
+		profile, secs, scoped := strings.Cut(value, "=")
+		if !scoped {
+			secs = profile
+		}
+		n, err := strconv.ParseFloat(secs, 64)
+		if err != nil || n < 0 {
+			return opts, &coral.Error{Code: coral.ErrCodeInvalidInput, Err: fmt.Errorf("invalid --stop-timeout %q: expected seconds or profile=seconds", value)}
+		}
+		d := time.Duration(n * float64(time.Second))
+		if !scoped {
+			opts.Timeout = d
+			continue
 		}
+		if profile != "drivers" && profile != "skillsets" && profile != "executors" {
+			return opts, &coral.Error{Code: coral.ErrCodeInvalidInput, Err: fmt.Errorf("invalid --stop-timeout %q: profile must be one of drivers, skillsets, executors", value)}
+		}
+		opts.ProfileTimeouts[profile] = d
 	}
-	if !found {
-		return fmt.Errorf("no instances found with group: %s", group)
-	}
-	fmt.Println(logging.Success("Done"))
-	return nil
+	return opts, nil
 }
 
-func extractProfiles(composePath string) ([]string, error) {
-	env := map[string]string{}
-	cf, err := compose.ParseCompose(composePath, env)
-	if err != nil {
-		return nil, fmt.Errorf("parsing compose file for profile extraction: %w", err)
-	}
-
-	profileSet := make(map[string]struct{})
-	for _, svc := range cf.Services {
-		if rawProfiles, ok := svc["profiles"]; ok {
-			switch p := rawProfiles.(type) {
-			case []interface{}:
-				for _, val := range p {
-					if str, ok := val.(string); ok {
-						profileSet[str] = struct{}{}
-					}
-				}
-			}
-		}
+// reports a graceful stop as it progresses: each service as it stops, and a countdown every five seconds (and over the last three) while services remain
+func printStopProgress(p coral.StopProgress) {
+	if p.Stopped != "" {
+		logging.Infof("Stopped %s", logging.BoldMagenta(p.Stopped))
+		return
 	}
-
-	var profiles []string
-	for p := range profileSet {
-		profiles = append(profiles, p)
+	secs := int(math.Ceil(p.Left.Seconds()))
+	if len(p.Remaining) == 0 || secs == 0 || (secs%5 != 0 && secs > 3) {
+		return
 	}
-	return orderedProfiles(profiles), nil
+	logging.Infof("Waiting %ds for %s %s to stop...", secs, p.Profile, strings.Join(p.Remaining, ", "))
 }
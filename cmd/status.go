@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"coral_cli/pkg/coral"
+)
+
+var statusSelector selectorFlags
+
+func init() {
+	statusCmd.Args = cobra.NoArgs
+
+	addSelectorFlags(statusCmd, &statusSelector, "report on")
+}
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Shows the state of every service in running Coral instances",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return status(cmd.Context(), &statusSelector)
+	},
+}
+
+// structured status of one instance
+type instanceStatus struct {
+	Instance  string                `json:"instance"`
+	Handle    string                `json:"handle,omitempty"`
+	Group     string                `json:"group,omitempty"`
+	Labels    map[string]string     `json:"labels,omitempty"`
+	Detached  bool                  `json:"detached"`
+	Abandoned bool                  `json:"abandoned,omitempty"`
+	Services  []coral.ServiceStatus `json:"services"`
+}
+
+// prints one row per service; with no criteria every instance is shown
+func status(ctx context.Context, sel *selectorFlags) error {
+	instances, err := sel.selectInstances(ctx, false)
+	if err != nil {
+		return err
+	}
+
+	result := []instanceStatus{}
+	for _, inst := range instances {
+		statuses, err := inst.Status(ctx)
+		if err != nil {
+			return fmt.Errorf("reading status of %s: %w", inst.Name, err)
+		}
+		result = append(result, instanceStatus{
+			Instance:  inst.Name,
+			Handle:    inst.Handle,
+			Group:     inst.Group,
+			Labels:    inst.Labels,
+			Detached:  inst.Detached,
+			Abandoned: inst.Abandoned,
+			Services:  statuses,
+		})
+	}
+	if outputFormat.Structured() {
+		return printResult(result)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "INSTANCE\tHANDLE\tGROUP\tLABELS\tPROFILE\tSERVICE\tSTATUS")
+	for _, inst := range result {
+		for _, st := range inst.Services {
+			state := st.Status
+			if state == "exited" || state == "dead" {
+				state = fmt.Sprintf("%s (%d)", state, st.ExitCode)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", inst.Instance, inst.Handle, inst.Group, formatLabels(inst.Labels), st.Profile, st.Service, state)
+		}
+	}
+	return w.Flush()
+}
+
+// renders labels as comma-separated key=value pairs in key order
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for _, key := range slices.Sorted(maps.Keys(labels)) {
+		pairs = append(pairs, key+"="+labels[key])
+	}
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"coral_cli/internal/logging"
+	"coral_cli/pkg/coral"
+)
+
+var (
+	upComposePath      string
+	upEnvFile          string
+	upHandle           string
+	upName             string
+	upGroup            string
+	upLabels           []string
+	upLibDir           string
+	upHealthTimeout    float32
+	upSkipVersionCheck bool
+)
+
+func init() {
+	upCmd.Args = cobra.NoArgs
+
+	upCmd.Flags().StringVarP(&upComposePath, "compose-file", "f", "", "Path to the Docker Compose .yaml file describing the desired services")
+	upCmd.Flags().StringVar(&upEnvFile, "env-file", "", "Optional path to .env file to use for compose substitutions")
+	upCmd.Flags().StringVar(&upHandle, "handle", "", "Handle of the instance to reconcile; a detached instance is launched with this handle if none has it")
+	upCmd.Flags().StringVarP(&upName, "name", "n", "", "Name of the instance to reconcile")
+	upCmd.Flags().StringVarP(&upGroup, "group", "g", "coral", "Group for the instance when one has to be launched")
+	upCmd.Flags().StringSliceVarP(&upLabels, "label", "l", []string{}, "Label for the instance as key=value when one has to be launched; repeat for several")
+	upCmd.Flags().StringVar(&upLibDir, "lib-dir", "", "Override CORAL_LIB path when an instance has to be launched (takes precedence over $CORAL_LIB environment variable)")
+	upCmd.Flags().Float32Var(&upHealthTimeout, "health-timeout", 120.0, "Seconds to wait for updated drivers/skillsets to become healthy before updating executors")
+	upCmd.Flags().BoolVar(&upSkipVersionCheck, "skip-version-check", false, "Skip coral.version compatibility check between CLI and images")
+
+	upCmd.RegisterFlagCompletionFunc("compose-file", completeComposeFiles)
+	upCmd.RegisterFlagCompletionFunc("handle", completeInstanceHandles)
+	upCmd.RegisterFlagCompletionFunc("name", completeInstanceNames)
+}
+
+var upCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Brings a running Coral instance in line with a compose file, changing only what differs",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		labels, err := parseLabels(upLabels)
+		if err != nil {
+			return err
+		}
+		return up(cmd.Context(), coral.LaunchOptions{
+			ComposePath:      upComposePath,
+			EnvFile:          upEnvFile,
+			Handle:           upHandle,
+			Group:            upGroup,
+			Labels:           labels,
+			Detached:         true,
+			HealthTimeout:    time.Duration(upHealthTimeout * float32(time.Second)),
+			LibDir:           upLibDir,
+			SkipVersionCheck: upSkipVersionCheck,
+		})
+	},
+}
+
+// reconciles the instance named by --name or --handle, launching a detached one when no instance has the handle yet
+func up(ctx context.Context, opts coral.LaunchOptions) error {
+	if (upName == "") == (upHandle == "") {
+		return &coral.Error{Code: coral.ErrCodeInvalidInput, Err: fmt.Errorf("exactly one of --name or --handle is required")}
+	}
+	inst, err := openByNameOrHandle(ctx, upName, upHandle)
+	if err != nil {
+		return err
+	}
+	if inst == nil {
+		logging.Infof("No instance has handle %s; launching one", logging.BoldMagenta(upHandle))
+		launched, err := coral.NewLauncher(Version).Launch(ctx, opts)
+		if err != nil {
+			return err
+		}
+		return printResult(newLaunchResult(launched))
+	}
+
+	result, err := coral.NewLauncher(Version).Reconcile(ctx, inst, opts)
+	if err != nil {
+		return err
+	}
+	if result.Empty() {
+		logging.Successf("%s is already up to date", describeInstance(inst))
+		return printResult(result)
+	}
+	for _, change := range []struct {
+		verb     string
+		services []string
+	}{
+		{"Added", result.Added},
+		{"Removed", result.Removed},
+		{"Recreated", result.Recreated},
+		{"Re-injected libraries into", result.Reinjected},
+	} {
+		if len(change.services) > 0 {
+			logging.Infof("%s %d service(s): %s", change.verb, len(change.services), logging.BoldMagenta(fmt.Sprintf("%v", change.services)))
+		}
+	}
+	logging.Successf("Reconciled %s", describeInstance(inst))
+	return printResult(result)
+}
@@ -1,15 +1,23 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"io/fs"
+	"maps"
 	"os"
-	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
 	"strings"
+	"syscall"
 
 	"github.com/spf13/cobra"
 
+	"coral_cli/internal/docker"
 	"coral_cli/internal/libs"
 	"coral_cli/internal/logging"
+	"coral_cli/pkg/coral"
 )
 
 var (
@@ -23,7 +31,7 @@ func init() {
 		if len(args) > 0 {
 			return nil, cobra.ShellCompDirectiveNoFileComp
 		}
-		out, err := exec.Command("docker", "images", "--format", "{{.Repository}}:{{.Tag}}").Output()
+		out, err := docker.CommandContext(cmd.Context(), docker.Query, "images", "--format", "{{.Repository}}:{{.Tag}}").Output()
 		if err != nil {
 			return nil, cobra.ShellCompDirectiveError
 		}
@@ -44,16 +52,28 @@ var verifyCmd = &cobra.Command{
 		if len(args) < 1 {
 			return fmt.Errorf("image name is required")
 		}
-		if err := verify(args[0], verifyLibDir); err != nil {
+		// a ctrl+c during extraction stops the probe copy and removes the probe container
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if err := verify(ctx, args[0], verifyLibDir); err != nil {
+			if outputFormat.Structured() {
+				return &coral.Error{Code: coral.ErrCodeVerification, Err: err}
+			}
 			return fmt.Errorf("%s: %w", logging.Failure("verification failed"), err)
 		}
-		fmt.Println(logging.Success("Image is compliant with Coral's standards"))
-		return nil
+		logging.Successf("Image is compliant with Coral's standards")
+		return printResult(verifyResult{Image: args[0], Compliant: true})
 	},
 }
 
-func verify(imageName string, libDir string) error {
-	if err := exec.Command("docker", "image", "inspect", imageName).Run(); err != nil {
+// structured result of a successful verification; failures are reported as error results
+type verifyResult struct {
+	Image     string `json:"image"`
+	Compliant bool   `json:"compliant"`
+}
+
+func verify(ctx context.Context, imageName string, libDir string) error {
+	if err := docker.CommandContext(ctx, docker.Query, "image", "inspect", imageName).Run(); err != nil {
 		return fmt.Errorf("docker image %q not found locally: %w", imageName, err)
 	}
 
@@ -67,11 +87,106 @@ func verify(imageName string, libDir string) error {
 		tmpLib = libDir
 	}
 
-	_, _, err = libs.ExtractLibraries(imageName, "verify", tmpLib)
+	stagingDir, _, err := libs.ExtractLibraries(ctx, imageName, "verify", tmpLib)
 	if err != nil {
 		return fmt.Errorf("extraction failed — ensure CORAL_EXPORT_LIB is set and contains behaviors/ and interfaces/: %w", err)
 	}
 
-	fmt.Println(logging.Info("CORAL_EXPORT_LIB is set and library extraction succeeded"))
+	logging.Infof("CORAL_EXPORT_LIB is set and library extraction succeeded")
+
+	platform, err := libs.GetImagePlatform(ctx, imageName)
+	if err != nil {
+		return err
+	}
+	problems, err := libs.CheckSharedObjects(stagingDir, platform)
+	if err != nil {
+		return fmt.Errorf("checking libraries: %w", err)
+	}
+	if len(problems) > 0 {
+		for _, file := range slices.Sorted(maps.Keys(problems)) {
+			logging.Failuref("%s: %s", file, problems[file])
+		}
+		return fmt.Errorf("%d libraries cannot be loaded on %s", len(problems), platform)
+	}
+	logging.Infof("Libraries are shared objects built for %s", platform)
+
+	if err := checkLibraryDependencies(ctx, imageName, stagingDir); err != nil {
+		return err
+	}
+	return checkNodeManifest(stagingDir)
+}
+
+// checks that the manifest, if the image ships one, is well-formed, only names libraries the image exports in behaviors/, and that every subtree it exports is a directory
+func checkNodeManifest(stagingDir string) error {
+	manifest, err := libs.ReadManifest(stagingDir)
+	if err != nil {
+		return err
+	}
+	if manifest == nil {
+		logging.Infof("No %s exported, so behavior trees cannot be checked against this image's nodes", libs.ManifestFile)
+		return nil
+	}
+	for _, node := range manifest.Nodes {
+		if node.Library == "" {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(stagingDir, "behaviors", node.Library)); err != nil {
+			return fmt.Errorf("%s: node %s names library %s, which is not exported in behaviors/", libs.ManifestFile, node.Name, node.Library)
+		}
+	}
+	for _, export := range manifest.Exports {
+		if info, err := os.Stat(filepath.Join(stagingDir, filepath.FromSlash(export))); err != nil || !info.IsDir() {
+			return fmt.Errorf("%s: exported %s is not a directory in CORAL_EXPORT_LIB", libs.ManifestFile, export)
+		}
+	}
+	logging.Infof("%s declares %d nodes and %d exported subtrees", libs.ManifestFile, len(manifest.Nodes), len(manifest.Exports))
+	return nil
+}
+
+// checks every exported shared object against the sonames of the image itself, and that every behavior exports a BT.CPP plugin entrypoint, reporting problems per library; an image whose sonames cannot be listed is reported and not checked, as at launch
+func checkLibraryDependencies(ctx context.Context, imageName, stagingDir string) error {
+	available, err := libs.ImageSharedObjects(ctx, imageName)
+	if err != nil {
+		// launches only warn when the image cannot be listed, so verify does too
+		if ctx.Err() != nil {
+			return err
+		}
+		logging.Warnf("Not checking library dependencies: %v", err)
+		return nil
+	}
+	var files []string
+	err = filepath.WalkDir(stagingDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && libs.IsSharedObjectName(d.Name()) {
+			available[d.Name()] = true
+			if d.Type()&fs.ModeSymlink == 0 {
+				files = append(files, path)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("listing libraries: %w", err)
+	}
+	broken := 0
+	for _, file := range files {
+		rel, _ := filepath.Rel(stagingDir, file)
+		problems, err := libs.CheckDependencies(file, strings.HasPrefix(rel, "behaviors"+string(filepath.Separator)), available)
+		if err != nil {
+			return fmt.Errorf("checking %s: %w", rel, err)
+		}
+		for _, problem := range problems {
+			logging.Failuref("%s %s", rel, problem)
+		}
+		if len(problems) > 0 {
+			broken++
+		}
+	}
+	if broken > 0 {
+		return fmt.Errorf("%d libraries may fail to load", broken)
+	}
+	logging.Infof("Library dependencies are present and behaviors export a plugin entrypoint")
 	return nil
 }
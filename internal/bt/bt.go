@@ -0,0 +1,269 @@
+// Package bt reads BT.CPP behavior-tree XML and checks it against the nodes payloads declare in their manifests.
+package bt
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// the kinds of node BT.CPP distinguishes
+const (
+	TypeAction    = "action"
+	TypeCondition = "condition"
+	TypeControl   = "control"
+	TypeDecorator = "decorator"
+)
+
+// the directions a port can have
+const (
+	PortInput  = "input"
+	PortOutput = "output"
+	PortInOut  = "inout"
+)
+
+// a node a payload's behavior libraries register with BT.CPP's factory, as declared in its manifest
+type NodeModel struct {
+	Name    string `yaml:"name" json:"name"`
+	Type    string `yaml:"type" json:"type"`                           // action, condition, control or decorator
+	Library string `yaml:"library,omitempty" json:"library,omitempty"` // file in behaviors/ that registers the node; any of the payload's behaviors when empty
+	Ports   []Port `yaml:"ports,omitempty" json:"ports,omitempty"`
+}
+
+// a port of a node
+type Port struct {
+	Name      string `yaml:"name" json:"name"`
+	Direction string `yaml:"direction,omitempty" json:"direction,omitempty"` // input (the default), output or inout
+	Required  bool   `yaml:"required,omitempty" json:"required,omitempty"`   // an input the tree must set, having no default
+}
+
+// rejects a model with no name, an unknown type or a malformed port
+func (n NodeModel) Validate() error {
+	if n.Name == "" {
+		return errors.New("node without a name")
+	}
+	switch n.Type {
+	case TypeAction, TypeCondition, TypeControl, TypeDecorator:
+	default:
+		return fmt.Errorf("node %s has invalid type %q: must be action, condition, control or decorator", n.Name, n.Type)
+	}
+	for _, p := range n.Ports {
+		if p.Name == "" {
+			return fmt.Errorf("node %s has a port without a name", n.Name)
+		}
+		switch p.Direction {
+		case "", PortInput, PortOutput, PortInOut:
+		default:
+			return fmt.Errorf("port %s of node %s has invalid direction %q: must be input, output or inout", p.Name, n.Name, p.Direction)
+		}
+	}
+	return nil
+}
+
+// nodes every BT.CPP factory registers itself (v3 and v4), which no payload needs to provide
+var builtinNodes = map[string]bool{
+	"AlwaysFailure": true, "AlwaysSuccess": true, "BlackboardCheckBool": true, "BlackboardCheckDouble": true,
+	"BlackboardCheckInt": true, "BlackboardCheckString": true, "Delay": true, "EntryUpdatedAction": true,
+	"Fallback": true, "ForceFailure": true, "ForceRunning": true, "ForceSuccess": true, "IfThenElse": true,
+	"Inverter": true, "KeepRunningUntilFailure": true, "LoopBool": true, "LoopDouble": true, "LoopInt": true,
+	"LoopString": true, "ManualSelector": true, "Parallel": true, "ParallelAll": true, "Precondition": true,
+	"ReactiveFallback": true, "ReactiveSequence": true, "Repeat": true, "RetryUntilSuccessful": true,
+	"RetryUntilSuccesful": true, "RunOnce": true, "Script": true, "ScriptCondition": true, "Sequence": true,
+	"SequenceStar": true, "SequenceWithMemory": true, "SetBlackboard": true, "SkipUnlessUpdated": true,
+	"Sleep": true, "Switch2": true, "Switch3": true, "Switch4": true, "Switch5": true, "Switch6": true,
+	"Timeout": true, "UnsetBlackboard": true, "WaitValueUpdate": true, "WasEntryUpdated": true,
+	"WhileDoElse": true,
+}
+
+// elements naming their node in an ID attribute (the BT.CPP v3 explicit form), mapped to the type they require
+var explicitTags = map[string]string{
+	"Action":    TypeAction,
+	"Condition": TypeCondition,
+	"Control":   TypeControl,
+	"Decorator": TypeDecorator,
+}
+
+// reports whether BT.CPP provides the named node itself
+func IsBuiltin(name string) bool {
+	return builtinNodes[name]
+}
+
+// one node of a tree that cannot be instantiated as written
+type Problem struct {
+	Tree    string `json:"tree"` // the BehaviorTree ID
+	Node    string `json:"node"`
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+	Unknown bool   `json:"unknown,omitempty"` // the node is not declared anywhere, but a library without a manifest may provide it, so it is not known to be missing
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("line %d, %s in tree %s: %s", p.Line, p.Node, p.Tree, p.Message)
+}
+
+// a node instance in a tree
+type element struct {
+	tag   string
+	attrs map[string]string
+	line  int
+}
+
+// the trees of a BT.CPP XML file
+type Document struct {
+	trees    map[string][]element // BehaviorTree ID → every node in it, in document order
+	order    []string             // tree IDs in document order
+	includes []string             // paths of <include> elements, whose trees are not read
+}
+
+// reads a BT.CPP XML file; TreeNodesModel sections are skipped, as they describe nodes rather than use them
+func Parse(r io.Reader) (*Document, error) {
+	doc := &Document{trees: map[string][]element{}}
+	dec := xml.NewDecoder(r)
+	var stack []string // open elements
+	tree := ""         // the BehaviorTree being read
+	skip := 0          // depth of the TreeNodesModel being skipped
+	for {
+		// taken before the token is read, so a start tag spanning several lines is reported at the line it opens on
+		line, _ := dec.InputPos()
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			name := t.Name.Local
+			depth := len(stack)
+			stack = append(stack, name)
+			switch {
+			case skip > 0:
+			case depth == 0:
+				if name != "root" {
+					return nil, fmt.Errorf("line %d: root element is <%s>, not <root>", line, name)
+				}
+			case depth == 1 && name == "TreeNodesModel":
+				skip = len(stack)
+			case depth == 1 && name == "include":
+				doc.includes = append(doc.includes, attr(t, "path"))
+			case depth == 1 && name == "BehaviorTree":
+				tree = attr(t, "ID")
+				if tree == "" {
+					tree = fmt.Sprintf("<unnamed at line %d>", line)
+				}
+				if _, exists := doc.trees[tree]; exists {
+					return nil, fmt.Errorf("line %d: tree %s is defined more than once", line, tree)
+				}
+				doc.trees[tree] = nil
+				doc.order = append(doc.order, tree)
+			case depth == 1:
+				return nil, fmt.Errorf("line %d: unexpected <%s> under <root>", line, name)
+			default:
+				attrs := make(map[string]string, len(t.Attr))
+				for _, a := range t.Attr {
+					attrs[a.Name.Local] = a.Value
+				}
+				doc.trees[tree] = append(doc.trees[tree], element{tag: name, attrs: attrs, line: line})
+			}
+		case xml.EndElement:
+			if skip == len(stack) {
+				skip = 0
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+	if len(doc.order) == 0 {
+		return nil, errors.New("no <BehaviorTree> found")
+	}
+	return doc, nil
+}
+
+func attr(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// checks every node of every tree in doc: a node must be built into BT.CPP or be among nodes, with the type its element requires, and may only set ports its model declares, including every required input; a subtree must be defined in doc unless doc includes other files. When partial is set, some injected libraries declare no nodes, so a node missing from nodes is reported as Unknown rather than as missing
+func Validate(doc *Document, nodes map[string]NodeModel, partial bool) []Problem {
+	var problems []Problem
+	for _, tree := range doc.order {
+		for _, e := range doc.trees[tree] {
+			report := func(node, format string, args ...any) {
+				problems = append(problems, Problem{Tree: tree, Node: node, Line: e.line, Message: fmt.Sprintf(format, args...)})
+			}
+			id, wantType := e.tag, ""
+			if t, explicit := explicitTags[e.tag]; explicit {
+				id, wantType = e.attrs["ID"], t
+			}
+			subtree := e.tag == "SubTree" || e.tag == "SubTreePlus"
+			if subtree {
+				id = e.attrs["ID"]
+			}
+			if id == "" {
+				report(e.tag, "no ID attribute")
+				continue
+			}
+			if subtree {
+				if _, defined := doc.trees[id]; !defined && len(doc.includes) == 0 {
+					report(id, "subtree is not defined in this file")
+				}
+				continue
+			}
+			if IsBuiltin(id) {
+				continue
+			}
+			model, ok := nodes[id]
+			if !ok && partial {
+				report(id, "no node manifest declares this node")
+				problems[len(problems)-1].Unknown = true
+				continue
+			}
+			if !ok {
+				report(id, "no injected behavior provides this node")
+				continue
+			}
+			if wantType != "" && model.Type != wantType {
+				report(id, "used as %s, but registered as %s", wantType, model.Type)
+			}
+			ports := make(map[string]Port, len(model.Ports))
+			for _, p := range model.Ports {
+				ports[p.Name] = p
+			}
+			for _, name := range slices.Sorted(maps.Keys(e.attrs)) {
+				// name and ID identify the node, and _-prefixed attributes are BT.CPP's own pre- and post-conditions
+				if name == "name" || name == "ID" || strings.HasPrefix(name, "_") {
+					continue
+				}
+				if _, declared := ports[name]; !declared {
+					report(id, "sets port %s, which is not declared (ports: %s)", name, portNames(model.Ports))
+				}
+			}
+			for _, p := range model.Ports {
+				if _, set := e.attrs[p.Name]; p.Required && !set && p.Direction != PortOutput {
+					report(id, "does not set required input port %s", p.Name)
+				}
+			}
+		}
+	}
+	return problems
+}
+
+func portNames(ports []Port) string {
+	if len(ports) == 0 {
+		return "none"
+	}
+	names := make([]string, len(ports))
+	for i, p := range ports {
+		names[i] = p.Name
+	}
+	return strings.Join(names, ", ")
+}
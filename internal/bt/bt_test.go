@@ -0,0 +1,234 @@
+package bt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		xml   string
+		err   string   // substring of the expected error; empty when parsing succeeds
+		trees []string // tree IDs in document order
+	}{
+		{
+			name: "trees in order",
+			xml: `<root BTCPP_format="4">
+  <BehaviorTree ID="Main"><AlwaysSuccess/></BehaviorTree>
+  <BehaviorTree ID="Sub"><AlwaysSuccess/></BehaviorTree>
+</root>`,
+			trees: []string{"Main", "Sub"},
+		},
+		{
+			name: "root element must be root",
+			xml:  `<tree><BehaviorTree ID="Main"/></tree>`,
+			err:  "root element is <tree>, not <root>",
+		},
+		{
+			name: "duplicate tree IDs",
+			xml: `<root>
+  <BehaviorTree ID="Main"><AlwaysSuccess/></BehaviorTree>
+  <BehaviorTree ID="Main"><AlwaysFailure/></BehaviorTree>
+</root>`,
+			err: "line 3: tree Main is defined more than once",
+		},
+		{
+			name: "no trees",
+			xml:  `<root><TreeNodesModel><Action ID="Grasp"/></TreeNodesModel></root>`,
+			err:  "no <BehaviorTree> found",
+		},
+		{
+			name: "unexpected element under root",
+			xml:  `<root><Sequence/></root>`,
+			err:  "unexpected <Sequence> under <root>",
+		},
+		{
+			name: "malformed XML",
+			xml:  `<root><BehaviorTree ID="Main">`,
+			err:  "unexpected EOF",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse(strings.NewReader(tt.xml))
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got error %v, want one containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(doc.order, ",") != strings.Join(tt.trees, ",") {
+				t.Errorf("got trees %v, want %v", doc.order, tt.trees)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	nodes := map[string]NodeModel{
+		"Grasp": {Name: "Grasp", Type: TypeAction, Ports: []Port{
+			{Name: "object", Required: true},
+			{Name: "force"},
+			{Name: "pose", Direction: PortOutput, Required: true},
+		}},
+		"IsHolding": {Name: "IsHolding", Type: TypeCondition},
+	}
+	type problem struct {
+		node    string
+		line    int
+		message string // substring of the problem's message
+		unknown bool
+	}
+	tests := []struct {
+		name     string
+		xml      string
+		partial  bool
+		problems []problem
+	}{
+		{
+			name: "declared nodes and builtins",
+			xml: `<root>
+  <BehaviorTree ID="Main">
+    <Sequence name="pick">
+      <IsHolding/>
+      <Grasp object="{cup}" force="2" name="grasp" _skipIf="done"/>
+    </Sequence>
+  </BehaviorTree>
+</root>`,
+		},
+		{
+			name: "TreeNodesModel is skipped",
+			xml: `<root>
+  <BehaviorTree ID="Main"><Grasp object="cup"/></BehaviorTree>
+  <TreeNodesModel>
+    <Action ID="Undeclared"><input_port name="x"/></Action>
+  </TreeNodesModel>
+</root>`,
+		},
+		{
+			name: "unknown node",
+			xml: `<root>
+  <BehaviorTree ID="Main">
+    <Place/>
+  </BehaviorTree>
+</root>`,
+			problems: []problem{{node: "Place", line: 3, message: "no injected behavior provides this node"}},
+		},
+		{
+			name: "explicit form with the right type",
+			xml: `<root>
+  <BehaviorTree ID="Main"><Action ID="Grasp" object="cup"/></BehaviorTree>
+</root>`,
+		},
+		{
+			name: "explicit form with a type mismatch",
+			xml: `<root>
+  <BehaviorTree ID="Main">
+    <Condition ID="Grasp" object="cup"/>
+  </BehaviorTree>
+</root>`,
+			problems: []problem{{node: "Grasp", line: 3, message: "used as condition, but registered as action"}},
+		},
+		{
+			name: "explicit form without an ID",
+			xml: `<root>
+  <BehaviorTree ID="Main"><Action name="grasp"/></BehaviorTree>
+</root>`,
+			problems: []problem{{node: "Action", line: 2, message: "no ID attribute"}},
+		},
+		{
+			name: "undeclared port",
+			xml: `<root>
+  <BehaviorTree ID="Main">
+    <Grasp object="cup" speed="3"/>
+  </BehaviorTree>
+</root>`,
+			problems: []problem{{node: "Grasp", line: 3, message: "sets port speed, which is not declared (ports: object, force, pose)"}},
+		},
+		{
+			name: "missing required input, output exempt",
+			xml: `<root>
+  <BehaviorTree ID="Main">
+    <Grasp force="1"/>
+  </BehaviorTree>
+</root>`,
+			problems: []problem{{node: "Grasp", line: 3, message: "does not set required input port object"}},
+		},
+		{
+			name: "start tag spanning lines reports its first line",
+			xml: `<root>
+  <BehaviorTree ID="Main">
+    <Grasp
+        object="cup"
+        speed="3"/>
+  </BehaviorTree>
+</root>`,
+			problems: []problem{{node: "Grasp", line: 3, message: "sets port speed"}},
+		},
+		{
+			name: "defined subtree",
+			xml: `<root>
+  <BehaviorTree ID="Main"><SubTree ID="Pick"/></BehaviorTree>
+  <BehaviorTree ID="Pick"><Grasp object="cup"/></BehaviorTree>
+</root>`,
+		},
+		{
+			name: "undefined subtree",
+			xml: `<root>
+  <BehaviorTree ID="Main">
+    <SubTree ID="Pick"/>
+  </BehaviorTree>
+</root>`,
+			problems: []problem{{node: "Pick", line: 3, message: "subtree is not defined in this file"}},
+		},
+		{
+			name: "undefined subtree with an include",
+			xml: `<root>
+  <include path="pick.xml"/>
+  <BehaviorTree ID="Main"><SubTree ID="Pick"/></BehaviorTree>
+</root>`,
+		},
+		{
+			name: "partial reports unresolved nodes as unknown",
+			xml: `<root>
+  <BehaviorTree ID="Main">
+    <Sequence>
+      <Place/>
+      <Condition ID="Grasp"/>
+    </Sequence>
+  </BehaviorTree>
+</root>`,
+			partial: true,
+			problems: []problem{
+				{node: "Place", line: 4, message: "no node manifest declares this node", unknown: true},
+				{node: "Grasp", line: 5, message: "used as condition, but registered as action"},
+				{node: "Grasp", line: 5, message: "does not set required input port object"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse(strings.NewReader(tt.xml))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := Validate(doc, nodes, tt.partial)
+			if len(got) != len(tt.problems) {
+				t.Fatalf("got problems %v, want %d", got, len(tt.problems))
+			}
+			for i, want := range tt.problems {
+				p := got[i]
+				if p.Node != want.node || p.Line != want.line || !strings.Contains(p.Message, want.message) || p.Unknown != want.unknown {
+					t.Errorf("problem %d is %+v, want %+v", i, p, want)
+				}
+				if p.Tree != "Main" {
+					t.Errorf("problem %d is in tree %s, want Main", i, p.Tree)
+				}
+			}
+		})
+	}
+}
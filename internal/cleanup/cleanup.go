@@ -1,68 +1,231 @@
 package cleanup
 
 import (
+	"context"
 	"fmt"
+	"math"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
-	"syscall"
+	"time"
 
 	"coral_cli/internal/compose"
+	"coral_cli/internal/docker"
 	"coral_cli/internal/health"
 	"coral_cli/internal/libs"
+	"coral_cli/internal/logging"
 	"coral_cli/internal/registry"
 	"coral_cli/internal/util"
 )
 
-func StopCompose(instanceName string, composePath string, kill bool, profiles []string) error {
+// configures StopCompose
+type StopOptions struct {
+	Kill            bool                     // kill containers straight away instead of stopping them gracefully
+	Timeout         time.Duration            // grace period before a container that has not stopped is killed; compose's default when zero
+	ProfileTimeouts map[string]time.Duration // per-profile grace periods overriding Timeout
+	Signal          string                   // signal sent to stop each container (e.g. SIGINT); the service's stop_signal (SIGTERM unless set) when empty
+	Services        map[string][]string      // services of each profile, used to report progress profile by profile
+	Escalate        <-chan struct{}          // closing it cuts a graceful stop short and kills whatever is still running
+	Progress        func(StopProgress)       // called as services stop and once a second with the time left; may be nil
+}
+
+// reports how a graceful stop is going
+type StopProgress struct {
+	Profile   string        // the profile being stopped
+	Stopped   string        // the service that just stopped, or empty for a countdown tick
+	Remaining []string      // services of the profile still running
+	Left      time.Duration // time until the remaining services are killed
+}
+
+// compose's own grace period, used for the countdown when no timeout is configured
+const defaultStopTimeout = 10 * time.Second
+
+// stops an instance's containers and removes them; a graceful stop goes through profiles in reverse start order (executors, then skillsets, then drivers) so executors can bring the robot to a safe state while the drivers they command are still up
+func StopCompose(ctx context.Context, instanceName string, composePath string, profiles []string, opts StopOptions) error {
 	args := []string{"compose", "-p", instanceName, "-f", composePath}
 	for _, profile := range profiles {
 		args = append(args, "--profile", profile)
 	}
 
+	kill := opts.Kill
+	if !kill {
+		for _, profile := range slices.Backward(profiles) {
+			escalated, err := stopProfile(ctx, instanceName, composePath, profile, opts)
+			if err != nil {
+				return fmt.Errorf("stopping %s: %w", profile, err)
+			}
+			if escalated {
+				kill = true
+				break
+			}
+		}
+	}
+
 	if kill {
-		killArgs := append(args, "kill")
-		killCmd := exec.Command("docker", killArgs...)
-		killCmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
-		killCmd.Stdout = os.Stdout
-		killCmd.Stderr = os.Stderr
+		killCmd := docker.CommandContext(ctx, docker.Compose, slices.Concat(args, []string{"kill"})...)
+		killCmd.Stdout = logging.CommandOutput()
+		killCmd.Stderr = logging.CommandErrors()
 		if err := killCmd.Run(); err != nil {
 			return fmt.Errorf("killing compose: %w", err)
 		}
 	}
 
-	downCmd := exec.Command("docker", append(args, "down")...)
-	downCmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
-	downCmd.Stdout = os.Stdout
-	downCmd.Stderr = os.Stderr
+	downCmd := docker.CommandContext(ctx, docker.Compose, slices.Concat(args, []string{"down"})...)
+	downCmd.Stdout = logging.CommandOutput()
+	downCmd.Stderr = logging.CommandErrors()
 	return downCmd.Run()
 }
 
+// gracefully stops the services of one profile while reporting each as it stops, killing any still running once the profile's grace period ends; returns true if opts.Escalate was closed first
+func stopProfile(ctx context.Context, instanceName, composePath, profile string, opts StopOptions) (bool, error) {
+	args := []string{"compose", "-p", instanceName, "-f", composePath, "--profile", profile}
+	grace, ok := opts.ProfileTimeouts[profile]
+	if !ok {
+		grace = opts.Timeout
+	}
+
+	running, _ := profileRunning(ctx, instanceName, profile, opts.Services)
+	stopCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// with no explicit signal compose stop does the whole job, including the kill when the grace period ends; otherwise the signal is sent here and the kill is left to the loop below
+	var done chan error
+	if opts.Signal == "" {
+		stopArgs := append(args, "stop")
+		if grace > 0 {
+			stopArgs = append(stopArgs, "--timeout", strconv.Itoa(int(math.Ceil(grace.Seconds()))))
+		}
+		done = make(chan error, 1)
+		go func() { done <- runQuietly(docker.CommandContext(stopCtx, docker.Compose, stopArgs...)) }()
+	} else if err := runQuietly(docker.CommandContext(ctx, docker.Compose, slices.Concat(args, []string{"kill", "-s", opts.Signal})...)); err != nil {
+		return false, err
+	}
+	if grace <= 0 {
+		grace = defaultStopTimeout
+	}
+
+	deadline := time.Now().Add(grace)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-done:
+			if err == nil {
+				reportStopped(opts.Progress, profile, running, nil)
+			}
+			return false, err
+		case <-opts.Escalate:
+			cancel()
+			if done != nil {
+				<-done
+			}
+			return true, nil
+		case <-ctx.Done():
+			// stopCtx is derived from ctx, so compose stop has been cancelled too
+			if done != nil {
+				<-done
+			}
+			return false, ctx.Err()
+		case <-ticker.C:
+			now, err := profileRunning(ctx, instanceName, profile, opts.Services)
+			if err != nil {
+				now = running
+			}
+			reportStopped(opts.Progress, profile, running, now)
+			running = now
+			// compose stop reports its own completion; after a signal, completion and the final kill are up to this loop
+			if done == nil {
+				if len(now) == 0 {
+					return false, nil
+				}
+				if time.Now().After(deadline) {
+					return false, runQuietly(docker.CommandContext(ctx, docker.Compose, slices.Concat(args, []string{"kill"})...))
+				}
+			}
+			if opts.Progress != nil && len(now) > 0 {
+				opts.Progress(StopProgress{Profile: profile, Remaining: now, Left: max(time.Until(deadline), 0)})
+			}
+		}
+	}
+}
+
+// runs a compose command whose per-container output would interleave with the progress reported by stopProfile, surfacing the output only on failure
+func runQuietly(cmd *docker.Cmd) error {
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w\n%s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// returns the running services of the profile, or of the whole instance when the profile's services are not known
+func profileRunning(ctx context.Context, instanceName, profile string, services map[string][]string) ([]string, error) {
+	running, err := health.RunningServices(ctx, instanceName)
+	if err != nil {
+		return nil, err
+	}
+	members, ok := services[profile]
+	if !ok {
+		return running, nil
+	}
+	var filtered []string
+	for _, svc := range running {
+		if slices.Contains(members, svc) {
+			filtered = append(filtered, svc)
+		}
+	}
+	return filtered, nil
+}
+
+// reports every service in before that is no longer in after
+func reportStopped(progress func(StopProgress), profile string, before, after []string) {
+	if progress == nil {
+		return
+	}
+	for _, svc := range before {
+		if !slices.Contains(after, svc) {
+			progress(StopProgress{Profile: profile, Stopped: svc, Remaining: after})
+		}
+	}
+}
+
+// removes every container of an instance without going through its compose file; used when the compose file is already gone
+func RemoveProjectContainers(ctx context.Context, instanceName string) error {
+	ids, err := health.GetContainerIDsForProject(ctx, instanceName)
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+	rmCmd := docker.CommandContext(ctx, docker.Compose, append([]string{"rm", "-f"}, ids...)...)
+	if out, err := rmCmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("removing containers: %w\n%s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
 // cleans up after a failed launch before instance metadata has been written; intended to be called from deferred functions in the launch path when instanceName is known
 func AbortInstance(instanceName, libPath, composePath string, reg *registry.Registry) {
 	stagingDirs, err := reg.RemoveExtractionsForInstance(instanceName)
 	if err != nil {
-		fmt.Printf("Warning: removing extraction records for %s: %v\n", instanceName, err)
+		logging.Warnf("Removing extraction records for %s: %v", instanceName, err)
 	}
 	for _, dir := range stagingDirs {
 		if err := os.RemoveAll(dir); err != nil {
-			fmt.Printf("Warning: removing staging dir %s: %v\n", dir, err)
+			logging.Warnf("Removing staging dir %s: %v", dir, err)
 		}
 	}
 	tryRemoveDirIfEmpty(filepath.Join(libPath, "staging"))
 	if err := reg.CleanupIfEmpty(); err != nil {
-		fmt.Printf("Warning: cleaning registry: %v\n", err)
+		logging.Warnf("Cleaning registry: %v", err)
 	}
 	if composePath != "" {
 		tryRemoveFileAndDirectory(composePath)
 	}
 }
 
-func RemoveInstanceFiles(instanceName string) error {
+func RemoveInstanceFiles(ctx context.Context, instanceName string) error {
 	meta, metaPath, err := util.LoadInstanceMetadata(instanceName)
 	if err != nil {
-		fmt.Printf("Error loading instance metadata: %v\n", err)
+		return fmt.Errorf("loading instance metadata: %w", err)
 	}
 	composeFile := meta.ComposeFile
 	libPath := meta.LibPath
@@ -73,40 +236,40 @@ func RemoveInstanceFiles(instanceName string) error {
 	// Load registry to remove extraction + injection records and staging dirs.
 	reg, regErr := registry.Load(libPath)
 	if regErr != nil {
-		fmt.Printf("Warning: could not load registry, skipping registry cleanup: %v\n", regErr)
+		logging.Warnf("Could not load registry, skipping registry cleanup: %v", regErr)
 		// Fall back to legacy docker-inspect-based cleanup.
-		cleanErr := legacyCleanupFromCompose(composeFile, libPath)
+		cleanErr := legacyCleanupFromCompose(ctx, composeFile, libPath)
 		tryRemoveDirIfEmpty(filepath.Join(libPath, "staging"))
 		return cleanErr
 	}
 
-	cleanErr := cleanupFromCompose(instanceName, reg)
+	cleanErr := cleanupFromCompose(ctx, instanceName, reg)
 	tryRemoveDirIfEmpty(filepath.Join(libPath, "staging"))
 	if err := reg.CleanupIfEmpty(); err != nil {
-		fmt.Printf("Warning: cleaning up registry: %v\n", err)
+		logging.Warnf("Cleaning up registry: %v", err)
 	}
 	return cleanErr
 }
 
 // removes staging directories and records using the registry for fast lookups rather than re-inspecting images
-func cleanupFromCompose(instanceName string, reg *registry.Registry) error {
+func cleanupFromCompose(ctx context.Context, instanceName string, reg *registry.Registry) error {
 	// Remove injection records for all containers in this instance.
-	containerIDs, _ := health.GetContainerIDsForProject(instanceName)
+	containerIDs, _ := health.GetContainerIDsForProject(ctx, instanceName)
 	for _, cid := range containerIDs {
 		if err := reg.RemoveInjection(cid); err != nil {
-			fmt.Printf("Warning: removing injection record for %s: %v\n", cid[:12], err)
+			logging.Warnf("Removing injection record for %s: %v", cid[:12], err)
 		}
 	}
 	// Belt-and-suspenders: also sweep by instanceID in case containers are already gone.
 	if err := reg.RemoveInjectionsForInstance(instanceName); err != nil {
-		fmt.Printf("Warning: sweeping injection records for %s: %v\n", instanceName, err)
+		logging.Warnf("Sweeping injection records for %s: %v", instanceName, err)
 	}
 
 	// Remove extraction records and staging directories (docker.yaml lives inside the
 	// staging dir, so os.RemoveAll handles it without a separate pass).
 	stagingDirs, err := reg.RemoveExtractionsForInstance(instanceName)
 	if err != nil {
-		fmt.Printf("Warning: removing extraction records for %s: %v\n", instanceName, err)
+		logging.Warnf("Removing extraction records for %s: %v", instanceName, err)
 	}
 	var warn string
 	for _, dir := range stagingDirs {
@@ -122,7 +285,7 @@ func cleanupFromCompose(instanceName string, reg *registry.Registry) error {
 }
 
 // pre-registry fallback used when the registry file cannot be loaded; re-inspects each image and removes the staging directory if found, without touching any registry
-func legacyCleanupFromCompose(composePath, libPath string) error {
+func legacyCleanupFromCompose(ctx context.Context, composePath, libPath string) error {
 	rawCompose, err := compose.LoadRawYAML(composePath)
 	if err != nil {
 		return fmt.Errorf("loading compose: %w", err)
@@ -141,9 +304,9 @@ func legacyCleanupFromCompose(composePath, libPath string) error {
 		if !ok || imageName == "" {
 			continue
 		}
-		imageID, err := libs.GetImageID(imageName)
+		imageID, err := libs.GetImageID(ctx, imageName)
 		if err != nil {
-			fmt.Printf("Skipping cleanup for %s: %v\n", name, err)
+			logging.Warnf("Skipping cleanup for %s: %v", name, err)
 			continue
 		}
 		imageID = imageID + "-coral-" + name
@@ -171,7 +334,7 @@ func tryRemoveDirIfEmpty(dir string) bool {
 		return false
 	}
 	if err := os.Remove(dir); err != nil {
-		fmt.Printf("Failed to remove directory %s: %v\n", dir, err)
+		logging.Failuref("Failed to remove directory %s: %v", dir, err)
 		return false
 	}
 	return true
@@ -48,9 +48,8 @@ func ResolveDevicePaths(df *DevicesFile, serviceName string) ([]string, error) {
 				serviceName, spec.VendorID, spec.ProductID, spec.Description)
 		}
 		if len(matches) > 1 {
-			fmt.Println(logging.Warning(fmt.Sprintf(
-				"%s: %d devices match USB %s:%s (%s) — mapping all",
-				serviceName, len(matches), spec.VendorID, spec.ProductID, spec.Description)))
+			logging.Warnf("%s: %d devices match USB %s:%s (%s) — mapping all",
+				serviceName, len(matches), spec.VendorID, spec.ProductID, spec.Description)
 		}
 		for _, group := range matches {
 			for _, d := range group {
@@ -2,6 +2,7 @@ package compose
 
 import (
 	"os"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -54,3 +55,26 @@ func SaveRawYAML(path string, content RawCompose) error {
 	}
 	return os.WriteFile(path, data, 0644)
 }
+
+// sets the given labels on a service, whether its existing labels are written as a map or as a list of key=value strings; the given values win over existing ones
+func SetLabels(service map[string]interface{}, labels map[string]string) {
+	if len(labels) == 0 {
+		return
+	}
+	merged := map[string]interface{}{}
+	switch existing := service["labels"].(type) {
+	case map[string]interface{}:
+		merged = existing
+	case []interface{}:
+		for _, entry := range existing {
+			if s, ok := entry.(string); ok {
+				key, value, _ := strings.Cut(s, "=")
+				merged[key] = value
+			}
+		}
+	}
+	for key, value := range labels {
+		merged[key] = value
+	}
+	service["labels"] = merged
+}
@@ -0,0 +1,145 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	"coral_cli/internal/logging"
+)
+
+// classifies a runtime command so it is bounded by the matching entry of Timeouts
+type Kind int
+
+const (
+	Query   Kind = iota // docker inspect, ps and similar lookups
+	Copy                // probe containers and docker cp of library trees
+	Pull                // image pulls
+	Compose             // docker compose create, up, start, kill and down, and pausing, killing or starting single containers
+	Wait                // docker wait on containers that run until their work is done; never bounded
+)
+
+// upper bounds on runtime commands by kind; a zero duration leaves that kind unbounded
+type Timeouts struct {
+	Query   time.Duration
+	Copy    time.Duration
+	Pull    time.Duration
+	Compose time.Duration
+}
+
+var DefaultTimeouts = Timeouts{
+	Query:   30 * time.Second,
+	Copy:    5 * time.Minute,
+	Pull:    30 * time.Minute,
+	Compose: 5 * time.Minute,
+}
+
+var timeouts = DefaultTimeouts
+
+// how long a cancelled command has to exit after being interrupted before it is killed
+const killDelay = 10 * time.Second
+
+// replaces the timeouts applied to commands created afterwards
+func SetTimeouts(t Timeouts) {
+	timeouts = t
+}
+
+func (t Timeouts) of(kind Kind) time.Duration {
+	switch kind {
+	case Query:
+		return t.Query
+	case Copy:
+		return t.Copy
+	case Pull:
+		return t.Pull
+	case Compose:
+		return t.Compose
+	}
+	return 0
+}
+
+// a docker invocation bound to a context; Run, Output, CombinedOutput and Wait trace the command at debug level and report cancellation and timeouts in their errors
+type Cmd struct {
+	*exec.Cmd
+	ctx     context.Context
+	cancel  context.CancelFunc
+	timeout time.Duration
+	started time.Time
+}
+
+// returns a docker invocation in its own process group so a ctrl+c on the terminal does not reach it and interrupt cleanup half-way; instead, cancelling ctx or exceeding the timeout for kind interrupts the whole group (so compose stops its own children) and kills it if it has not exited within killDelay
+func CommandContext(ctx context.Context, kind Kind, args ...string) *Cmd {
+	timeout := timeouts.of(kind)
+	cancel := context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGINT)
+	}
+	cmd.WaitDelay = killDelay
+	return &Cmd{Cmd: cmd, ctx: ctx, cancel: cancel, timeout: timeout}
+}
+
+func (c *Cmd) Run() error {
+	c.started = time.Now()
+	return c.finish(c.Cmd.Run())
+}
+
+func (c *Cmd) Output() ([]byte, error) {
+	c.started = time.Now()
+	out, err := c.Cmd.Output()
+	return out, c.finish(err)
+}
+
+func (c *Cmd) CombinedOutput() ([]byte, error) {
+	c.started = time.Now()
+	out, err := c.Cmd.CombinedOutput()
+	return out, c.finish(err)
+}
+
+// starts a streaming command (e.g. docker images piped through a filter); the caller must call Wait
+func (c *Cmd) Start() error {
+	c.started = time.Now()
+	if err := c.Cmd.Start(); err != nil {
+		c.cancel()
+		logging.Debugf("exec %s failed to start: %v", commandLine(c.Cmd), err)
+		return err
+	}
+	logging.Debugf("exec %s started (pid %d)", commandLine(c.Cmd), c.Process.Pid)
+	return nil
+}
+
+func (c *Cmd) Wait() error {
+	return c.finish(c.Cmd.Wait())
+}
+
+// releases the command's timeout, logs it with its duration and outcome at debug level, and attaches the context's error to err when the command was cut short
+func (c *Cmd) finish(err error) error {
+	ctxErr := c.ctx.Err()
+	c.cancel()
+	if err != nil && ctxErr != nil {
+		if errors.Is(ctxErr, context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %s: %w (%w)", c.timeout, err, ctxErr)
+		} else {
+			err = fmt.Errorf("%w (%w)", err, ctxErr)
+		}
+	}
+	elapsed := time.Since(c.started).Round(time.Millisecond)
+	if err != nil {
+		logging.Debugf("exec %s failed after %s: %v", commandLine(c.Cmd), elapsed, err)
+		return err
+	}
+	logging.Debugf("exec %s took %s", commandLine(c.Cmd), elapsed)
+	return nil
+}
+
+func commandLine(cmd *exec.Cmd) string {
+	return strings.Join(cmd.Args, " ")
+}
@@ -0,0 +1,139 @@
+package docker
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/fatih/color"
+
+	"coral_cli/internal/logging"
+	"coral_cli/internal/util"
+)
+
+func TailLogs(containers []util.ContainerInfo, doneChan <-chan struct{}, tailAll bool) (<-chan struct{}, <-chan error) {
+	colors := []color.Attribute{
+		color.FgHiRed,
+		color.FgHiGreen,
+		color.FgHiYellow,
+		color.FgHiBlue,
+		color.FgHiMagenta,
+		color.FgHiCyan,
+		color.FgHiWhite,
+		color.FgHiBlack,
+	}
+	colorMap := make(map[string]*color.Color)
+	serviceCounts := make(map[string]int)
+	colorIndex := 0
+
+	printMu := &sync.Mutex{}
+	errCh := make(chan error, len(containers))
+	finished := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for _, c := range containers {
+		key := c.Service
+		count := serviceCounts[key]
+		if count > 0 {
+			key = fmt.Sprintf("%s-%d", key, count)
+		}
+		serviceCounts[c.Service] = count + 1
+
+		clr, exists := colorMap[key]
+		if !exists {
+			clr = color.New(colors[colorIndex%len(colors)]).Add(color.Bold)
+			colorMap[key] = clr
+			colorIndex++
+		}
+
+		wg.Add(1)
+		go func(c util.ContainerInfo, clr *color.Color) {
+			defer wg.Done()
+
+			// not started in its own process group, so ctrl+c reaches docker logs directly (exit code 130 below)
+			cmd := exec.Command("docker", "logs", "-f", c.ID)
+			if !tailAll {
+				cmd = exec.Command("docker", "logs", "-f", "--since", "0s", "--tail", "0", c.ID)
+			}
+			stdout, _ := cmd.StdoutPipe()
+			stderr, _ := cmd.StderrPipe()
+
+			if err := cmd.Start(); err != nil {
+				errCh <- fmt.Errorf("failed to start logs for %s: %w", c.Name, err)
+				return
+			}
+			logging.Debugf("exec %s started (pid %d)", commandLine(cmd), cmd.Process.Pid)
+
+			printStream := func(r io.Reader) {
+				scanner := bufio.NewScanner(r)
+				for scanner.Scan() {
+					line := scanner.Text()
+					printMu.Lock()
+					logging.ContainerLine(key, clr, line)
+					printMu.Unlock()
+				}
+			}
+
+			go printStream(stdout)
+			go printStream(stderr)
+
+			if err := cmd.Wait(); err != nil {
+				if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 130 {
+					return
+				}
+				errCh <- fmt.Errorf("logs exited for %s: %w", c.Name, err)
+			}
+		}(c, clr)
+	}
+
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+
+	return finished, errCh
+
+}
+
+func GetContainerInfo(ctx context.Context, instanceName string, composePath string) ([]util.ContainerInfo, error) {
+	var containers []util.ContainerInfo
+
+	args := []string{"compose", "-p", instanceName, "-f", composePath, "ps", "-q"}
+	out, err := CommandContext(ctx, Query, args...).Output()
+	if err != nil {
+		return containers, fmt.Errorf("failed to get container IDs: %w", err)
+	}
+	containerIDs := strings.Fields(string(out))
+	if len(containerIDs) == 0 {
+		return containers, fmt.Errorf("no containers found for instance %s", instanceName)
+	}
+
+	// inspect containers to get service names
+	prefix := instanceName + "-"
+	suffixRegex := regexp.MustCompile(`-\d+$`)
+	for _, id := range containerIDs {
+		nameOut, err := CommandContext(ctx, Query, "inspect", "-f", "{{.Name}}", id).Output()
+		if err != nil {
+			return containers, fmt.Errorf("failed to inspect container %s: %w", id, err)
+		}
+		fullName := strings.Trim(strings.TrimSpace(string(nameOut)), "/")
+		serviceName := fullName
+		if strings.HasPrefix(fullName, prefix) {
+			serviceName = fullName[len(prefix):]
+		}
+		serviceName = suffixRegex.ReplaceAllString(serviceName, "")
+
+		containers = append(containers, util.ContainerInfo{
+			ID:      id,
+			Name:    fullName,
+			Service: serviceName,
+		})
+	}
+
+	return containers, nil
+}
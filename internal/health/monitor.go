@@ -3,12 +3,11 @@ package health
 import (
 	"context"
 	"fmt"
-	"os/exec"
 	"strconv"
 	"strings"
-	"syscall"
 	"time"
 
+	"coral_cli/internal/docker"
 	"coral_cli/internal/logging"
 	"coral_cli/internal/registry"
 )
@@ -20,6 +19,8 @@ const (
 	EventContainerExited
 	// fires when a payload that contributed libraries to an executor is no longer running its skillset/driver backend; the executor itself is unaffected(libraries are already inside the container), but the behaviors may fail at runtime
 	EventLibraryDegraded
+	// fires when a container previously reported unhealthy or exited is healthy (or running, without a health check) again
+	EventContainerRecovered
 )
 
 // emitted by the Monitor when a container or library dependency degrades
@@ -27,7 +28,10 @@ type HealthEvent struct {
 	Type        EventType
 	ContainerID string
 	ServiceName string
-	PayloadID   string // set for EventLibraryDegraded
+	Profile     string            // the service's coral.profile
+	Critical    bool              // whether the service's image is labelled coral.critical=true
+	Labels      map[string]string // the instance's user labels
+	PayloadID   string            // set for EventLibraryDegraded
 	Detail      string
 }
 
@@ -36,11 +40,13 @@ const pollInterval = 5 * time.Second
 // polls Docker container health for a compose instance and emits HealthEvents when containers become unhealthy or library backends are lost
 type Monitor struct {
 	instanceName string
+	labels       map[string]string
 	reg          *registry.Registry
 }
 
-func NewMonitor(instanceName string, reg *registry.Registry) *Monitor {
-	return &Monitor{instanceName: instanceName, reg: reg}
+// labels are the instance's user labels, attached to every event
+func NewMonitor(instanceName string, labels map[string]string, reg *registry.Registry) *Monitor {
+	return &Monitor{instanceName: instanceName, labels: labels, reg: reg}
 }
 
 func (m *Monitor) Start(ctx context.Context) <-chan HealthEvent {
@@ -55,35 +61,41 @@ func (m *Monitor) Start(ctx context.Context) <-chan HealthEvent {
 			case <-ctx.Done():
 				return
 			case <-ticker.C:
-				m.poll(events, flagged)
+				m.poll(ctx, events, flagged)
 			}
 		}
 	}()
 	return events
 }
 
-func (m *Monitor) poll(events chan<- HealthEvent, flagged map[string]bool) {
-	ids, err := GetContainerIDsForProject(m.instanceName)
+func (m *Monitor) poll(ctx context.Context, events chan<- HealthEvent, flagged map[string]bool) {
+	ids, err := GetContainerIDsForProject(ctx, m.instanceName)
 	if err != nil || len(ids) == 0 {
 		return
 	}
 	for _, id := range ids {
+		cs := containerStatus(ctx, id)
 		if flagged[id] {
+			// a restarted or recovered container is watched again from scratch
+			if cs.status == "healthy" || cs.status == "running_no_healthcheck" {
+				delete(flagged, id)
+				events <- HealthEvent{Type: EventContainerRecovered, ContainerID: id, ServiceName: cs.serviceName, Profile: cs.profile, Critical: cs.critical, Labels: m.labels, Detail: "container recovered"}
+				logging.Infof("Container %s (%s) has recovered", shortID(id), cs.serviceName)
+			}
 			continue
 		}
-		cs := containerStatus(id)
 		switch cs.status {
 		case "unhealthy":
 			flagged[id] = true
-			events <- HealthEvent{Type: EventContainerUnhealthy, ContainerID: id, ServiceName: cs.serviceName, Detail: "health check failing"}
-			fmt.Println(logging.Warning(fmt.Sprintf("Container %s (%s) is unhealthy", shortID(id), cs.serviceName)))
+			events <- HealthEvent{Type: EventContainerUnhealthy, ContainerID: id, ServiceName: cs.serviceName, Profile: cs.profile, Critical: cs.critical, Labels: m.labels, Detail: "health check failing"}
+			logging.Warnf("Container %s (%s) is unhealthy", shortID(id), cs.serviceName)
 		case "exited", "dead":
 			flagged[id] = true
 			if cs.transient && cs.exitCode == 0 {
 				continue
 			}
-			events <- HealthEvent{Type: EventContainerExited, ContainerID: id, ServiceName: cs.serviceName, Detail: "container exited"}
-			fmt.Println(logging.Warning(fmt.Sprintf("Container %s (%s) has exited unexpectedly", shortID(id), cs.serviceName)))
+			events <- HealthEvent{Type: EventContainerExited, ContainerID: id, ServiceName: cs.serviceName, Profile: cs.profile, Critical: cs.critical, Labels: m.labels, Detail: "container exited"}
+			logging.Warnf("Container %s (%s) has exited unexpectedly", shortID(id), cs.serviceName)
 			// check whether any executor injections depended on this container's payload
 			m.checkLibraryDegradation(id, cs.serviceName, events)
 		}
@@ -101,12 +113,11 @@ func (m *Monitor) checkLibraryDegradation(deadContainerID, svcName string, event
 			ContainerID: rec.ContainerID,
 			ServiceName: svcName,
 			PayloadID:   svcName,
+			Labels:      m.labels,
 			Detail:      fmt.Sprintf("backend service %s exited; injected libraries may fail at runtime", svcName),
 		}
-		fmt.Println(logging.Warning(fmt.Sprintf(
-			"Executor %s has libraries from %s which has exited — behaviors may fail at runtime",
-			shortID(rec.ContainerID), svcName,
-		)))
+		logging.Warnf("Executor %s has libraries from %s which has exited — behaviors may fail at runtime",
+			shortID(rec.ContainerID), svcName)
 	}
 }
 
@@ -119,12 +130,12 @@ func WaitForHealthy(ctx context.Context, instanceName string, services []string,
 		}
 		allReady := true
 		for _, svc := range services {
-			id, err := GetContainerIDForService(instanceName, svc)
+			id, err := GetContainerIDForService(ctx, instanceName, svc)
 			if err != nil || id == "" {
 				allReady = false
 				break
 			}
-			cs := containerStatus(id)
+			cs := containerStatus(ctx, id)
 			if (cs.status == "exited" || cs.status == "dead") && !isReady(cs) {
 				return fmt.Errorf("service %s exited unexpectedly (exit code %d)", svc, cs.exitCode)
 			}
@@ -148,8 +159,10 @@ func WaitForHealthy(ctx context.Context, instanceName string, services []string,
 type containerState struct {
 	status      string
 	serviceName string
+	profile     string
 	exitCode    int
 	transient   bool
+	critical    bool
 }
 
 func isReady(cs containerState) bool {
@@ -162,37 +175,25 @@ func isReady(cs containerState) bool {
 	return false
 }
 
-// returns normalised state for a container, including exit code and whether it bears the coral.transient label
-func containerStatus(containerID string) containerState {
-	cmd := exec.Command("docker", "inspect",
-		"--format", `{{if .State.Health}}{{.State.Health.Status}}{{else}}none{{end}} {{.State.Status}} {{index .Config.Labels "com.docker.compose.service"}} {{.State.ExitCode}} {{index .Config.Labels "coral.transient"}}`,
+// returns normalised state for a container, including exit code, profile and whether it bears the coral.transient and coral.critical labels
+func containerStatus(ctx context.Context, containerID string) containerState {
+	cmd := docker.CommandContext(ctx, docker.Query, "inspect",
+		"--format", `{{if .State.Health}}{{.State.Health.Status}}{{else}}none{{end}}|{{.State.Status}}|{{index .Config.Labels "com.docker.compose.service"}}|{{.State.ExitCode}}|{{index .Config.Labels "coral.transient"}}|{{index .Config.Labels "coral.profile"}}|{{index .Config.Labels "coral.critical"}}`,
 		containerID)
-	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
 	out, err := cmd.Output()
 	if err != nil {
 		return containerState{status: "unknown"}
 	}
-	parts := strings.Fields(strings.TrimSpace(string(out)))
-	var health, state, svc string
-	var exitCode int
-	var transient bool
-	if len(parts) > 0 {
-		health = parts[0]
-	}
-	if len(parts) > 1 {
-		state = parts[1]
-	}
-	if len(parts) > 2 {
-		svc = parts[2]
-	}
-	if len(parts) > 3 {
-		if n, err := strconv.Atoi(parts[3]); err == nil {
-			exitCode = n
+	// labels may be empty, so fields are separated explicitly rather than by whitespace
+	parts := strings.Split(strings.TrimSpace(string(out)), "|")
+	field := func(i int) string {
+		if i < len(parts) {
+			return strings.TrimSpace(parts[i])
 		}
+		return ""
 	}
-	if len(parts) > 4 {
-		transient = parts[4] == "true"
-	}
+	health, state := field(0), field(1)
+	exitCode, _ := strconv.Atoi(field(3))
 	var status string
 	if health == "" || health == "none" {
 		if state == "running" {
@@ -203,16 +204,28 @@ func containerStatus(containerID string) containerState {
 	} else {
 		status = health
 	}
-	return containerState{status: status, serviceName: svc, exitCode: exitCode, transient: transient}
+	return containerState{
+		status:      status,
+		serviceName: field(2),
+		profile:     field(5),
+		exitCode:    exitCode,
+		transient:   field(4) == "true",
+		critical:    field(6) == "true",
+	}
+}
+
+// returns the normalised status (healthy, unhealthy, starting, running_no_healthcheck, exited, ...) and exit code of a container
+func InspectStatus(ctx context.Context, containerID string) (string, int) {
+	cs := containerStatus(ctx, containerID)
+	return cs.status, cs.exitCode
 }
 
-func GetContainerIDForService(instanceName, serviceName string) (string, error) {
-	cmd := exec.Command("docker", "ps", "-a",
+func GetContainerIDForService(ctx context.Context, instanceName, serviceName string) (string, error) {
+	cmd := docker.CommandContext(ctx, docker.Query, "ps", "-a",
 		"--filter", fmt.Sprintf("label=com.docker.compose.project=%s", instanceName),
 		"--filter", fmt.Sprintf("label=com.docker.compose.service=%s", serviceName),
 		"--filter", "label=com.docker.compose.oneoff=False",
 		"-q")
-	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
 	out, err := cmd.Output()
 	if err != nil {
 		return "", err
@@ -220,12 +233,11 @@ func GetContainerIDForService(instanceName, serviceName string) (string, error)
 	return strings.TrimSpace(string(out)), nil
 }
 
-func GetContainerIDsForProject(instanceName string) ([]string, error) {
-	cmd := exec.Command("docker", "ps", "-a",
+func GetContainerIDsForProject(ctx context.Context, instanceName string) ([]string, error) {
+	cmd := docker.CommandContext(ctx, docker.Query, "ps", "-a",
 		"--filter", fmt.Sprintf("label=com.docker.compose.project=%s", instanceName),
 		"--filter", "label=com.docker.compose.oneoff=False",
 		"-q")
-	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
 	out, err := cmd.Output()
 	if err != nil {
 		return nil, err
@@ -239,6 +251,26 @@ func GetContainerIDsForProject(instanceName string) ([]string, error) {
 	return ids, nil
 }
 
+// returns the compose services of an instance whose containers are currently running
+func RunningServices(ctx context.Context, instanceName string) ([]string, error) {
+	cmd := docker.CommandContext(ctx, docker.Query, "ps",
+		"--filter", fmt.Sprintf("label=com.docker.compose.project=%s", instanceName),
+		"--filter", "label=com.docker.compose.oneoff=False",
+		"--filter", "status=running",
+		"--format", `{{.Label "com.docker.compose.service"}}`)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	var services []string
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			services = append(services, line)
+		}
+	}
+	return services, nil
+}
+
 func shortID(id string) string {
 	if len(id) > 12 {
 		return id[:12]
@@ -0,0 +1,166 @@
+package libs
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path"
+	"slices"
+	"sync"
+
+	"coral_cli/internal/logging"
+)
+
+// a tar archive of the files chosen for an injection, spooled to a temporary file, with the SHA-256 of each keyed by subdirectory/name
+type libraryArchive struct {
+	path      string
+	size      int64
+	checksums map[string]string
+}
+
+func (a *libraryArchive) open() (*os.File, error) {
+	return os.Open(a.path)
+}
+
+// keeps the archives InjectLibraries builds so executors given identical library sets share one, along with the shared objects of every image it probed. Archives are spooled to temporary files rather than held in memory, and Close removes them, so a cache should live no longer than one launch, reconcile or mission
+type ArchiveCache struct {
+	mu       sync.Mutex
+	archives map[string]*libraryArchive // archiveKey → archive
+	sonames  map[string]map[string]bool // image ID → shared object file names
+}
+
+func NewArchiveCache() *ArchiveCache {
+	return &ArchiveCache{
+		archives: make(map[string]*libraryArchive),
+		sonames:  make(map[string]map[string]bool),
+	}
+}
+
+// returns the shared objects of an image, probing it unless it was probed before; the result must not be modified
+func (c *ArchiveCache) sharedObjects(ctx context.Context, image string) (map[string]bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if names, ok := c.sonames[image]; ok {
+		logging.Debugf("Reusing the %d shared objects listed in %s", len(names), image)
+		return names, nil
+	}
+	names, err := ImageSharedObjects(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+	c.sonames[image] = names
+	return names, nil
+}
+
+// removes the temporary files of every archive in the cache, which stays usable afterwards
+func (c *ArchiveCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var errs []error
+	for key, a := range c.archives {
+		if err := os.Remove(a.path); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+		}
+		delete(c.archives, key)
+	}
+	return errors.Join(errs...)
+}
+
+// returns the archive of winners with every entry below root, building it unless an identical one was built before
+func (c *ArchiveCache) archive(root string, winners map[string]map[string]libEntry) (*libraryArchive, error) {
+	key := archiveKey(root, winners)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if a, ok := c.archives[key]; ok {
+		logging.Debugf("Reusing library archive of %d files (%d bytes)", len(a.checksums), a.size)
+		return a, nil
+	}
+	a, err := buildArchive(root, winners)
+	if err != nil {
+		return nil, err
+	}
+	c.archives[key] = a
+	return a, nil
+}
+
+// identifies a set of winning files; staging directories never change once extracted, so a file is identified by its path and modification time
+func archiveKey(root string, winners map[string]map[string]libEntry) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n", root)
+	for _, subDir := range slices.Sorted(maps.Keys(winners)) {
+		for _, name := range slices.Sorted(maps.Keys(winners[subDir])) {
+			entry := winners[subDir][name]
+			fmt.Fprintf(h, "%s/%s\t%s\t%d\n", subDir, name, entry.srcPath, entry.mtime.UnixNano())
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// writes every winning file to a tar archive below root in a temporary file, hashing each as it is read. Symlinks are archived as the files they point to, and modification times are preserved so conflict resolution is stable on re-injection; directories are left for docker to create
+func buildArchive(root string, winners map[string]map[string]libEntry) (a *libraryArchive, err error) {
+	f, err := os.CreateTemp("", "coral-libs-*.tar")
+	if err != nil {
+		return nil, fmt.Errorf("creating library archive: %w", err)
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(f.Name())
+			a = nil
+		}
+	}()
+
+	tw := tar.NewWriter(f)
+	checksums := make(map[string]string)
+	for _, subDir := range slices.Sorted(maps.Keys(winners)) {
+		for _, name := range slices.Sorted(maps.Keys(winners[subDir])) {
+			sum, err := archiveFile(tw, path.Join(root, subDir, name), winners[subDir][name].srcPath)
+			if err != nil {
+				return nil, fmt.Errorf("archiving %s/%s: %w", subDir, name, err)
+			}
+			checksums[subDir+"/"+name] = sum
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return &libraryArchive{path: f.Name(), size: info.Size(), checksums: checksums}, nil
+}
+
+func archiveFile(tw *tar.Writer, name, src string) (string, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	if err := tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     int64(info.Mode().Perm()),
+		Size:     info.Size(),
+		ModTime:  info.ModTime(),
+	}); err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tw, h), f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
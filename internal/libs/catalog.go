@@ -0,0 +1,96 @@
+package libs
+
+import (
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+
+	"coral_cli/internal/registry"
+)
+
+// the kinds of catalog entry
+const (
+	KindNode      = "node"      // a BT.CPP node declared in a payload's manifest
+	KindBehavior  = "behavior"  // a library in behaviors/
+	KindInterface = "interface" // a library in interfaces/
+	KindFile      = "file"      // a file in an exported subtree
+)
+
+// one payload's provision of a node or library
+type CatalogEntry struct {
+	Kind       string   `json:"kind"`
+	Name       string   `json:"name"`              // the node name, the library's file name, or an exported file's path relative to CORAL_EXPORT_LIB
+	Type       string   `json:"type,omitempty"`    // the node's type
+	Library    string   `json:"library,omitempty"` // the behavior library registering the node, if its manifest names one
+	PayloadID  string   `json:"payload_id"`
+	Shadowed   bool     `json:"shadowed,omitempty"`    // another payload's library of the same name would be injected instead
+	ShadowedBy string   `json:"shadowed_by,omitempty"` // the payload whose library would be injected
+	Decision   string   `json:"decision,omitempty"`    // how a library name provided by several payloads is resolved: identical, priority, newest or order
+	Clashes    []string `json:"clashes,omitempty"`     // other payloads whose injected libraries register the same node
+}
+
+// lists every node, library and exported file the payloads in sources provide, resolving libraries with the same name by policy as an injection of all of them would (without checking platforms). A node whose library is shadowed is shadowed with it, and a node that several payloads' injected libraries declare names the others as clashes, since BT.CPP refuses to register a node twice
+func Catalog(sources map[string]LibrarySource, policy ConflictPolicy) ([]CatalogEntry, error) {
+	_, planned, err := planLibraries(sources, policy, Platform{}, false)
+	if err != nil {
+		return nil, err
+	}
+	var entries []CatalogEntry
+	behaviors := make(map[string]map[string]registry.InjectedLib) // payload → behavior file name → record
+	for _, lib := range planned {
+		kind, name := KindBehavior, lib.LibName
+		switch lib.SubDir {
+		case "behaviors":
+		case "interfaces":
+			kind = KindInterface
+		default:
+			kind, name = KindFile, path.Join(lib.SubDir, lib.LibName)
+		}
+		entries = append(entries, CatalogEntry{
+			Kind:       kind,
+			Name:       name,
+			PayloadID:  lib.PayloadID,
+			Shadowed:   lib.Shadowed,
+			ShadowedBy: lib.ShadowedBy,
+			Decision:   lib.Decision,
+		})
+		if kind == KindBehavior {
+			if behaviors[lib.PayloadID] == nil {
+				behaviors[lib.PayloadID] = map[string]registry.InjectedLib{}
+			}
+			behaviors[lib.PayloadID][lib.LibName] = lib
+		}
+	}
+
+	var nodes []CatalogEntry
+	for _, payloadID := range slices.Sorted(maps.Keys(sources)) {
+		manifest, err := ReadManifest(sources[payloadID].Dir)
+		if err != nil {
+			return nil, fmt.Errorf("reading manifest of %s: %w", payloadID, err)
+		}
+		if manifest == nil {
+			continue
+		}
+		for _, n := range manifest.Nodes {
+			entry := CatalogEntry{Kind: KindNode, Name: n.Name, Type: n.Type, Library: n.Library, PayloadID: payloadID}
+			if lib, ok := behaviors[payloadID][n.Library]; ok && lib.Shadowed {
+				entry.Shadowed, entry.ShadowedBy, entry.Decision = true, lib.ShadowedBy, lib.Decision
+			}
+			nodes = append(nodes, entry)
+		}
+	}
+	// nodes registered by libraries that would all be injected clash in BT.CPP's factory
+	registrants := make(map[string][]string) // node name → payloads
+	for _, n := range nodes {
+		if !n.Shadowed {
+			registrants[n.Name] = append(registrants[n.Name], n.PayloadID)
+		}
+	}
+	for i, n := range nodes {
+		if !n.Shadowed {
+			nodes[i].Clashes = slices.DeleteFunc(slices.Clone(registrants[n.Name]), func(id string) bool { return id == n.PayloadID })
+		}
+	}
+	return append(entries, nodes...), nil
+}
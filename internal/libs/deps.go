@@ -0,0 +1,72 @@
+package libs
+
+import (
+	"context"
+	"debug/elf"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"coral_cli/internal/docker"
+)
+
+// the functions BT.CPP's factory calls to register a plugin's nodes (BT_REGISTER_NODES and its ROS 2 counterpart); a behavior library must export one of them
+var pluginEntrypoints = []string{"BT_RegisterNodesFromPlugin", "BT_RegisterRosNodeFromPlugin"}
+
+// returns the file names of every shared object in an image (e.g. libfoo.so.1), found by running find over its filesystem in a throwaway container; the image must provide find
+func ImageSharedObjects(ctx context.Context, image string) (map[string]bool, error) {
+	uid := uuid.New()
+	probeName := fmt.Sprintf("coral-probe-%x", uid[:4])
+	cmd := docker.CommandContext(ctx, docker.Copy, "run", "--rm", "--name", probeName, "--entrypoint", "find", image,
+		"/", "-xdev", "-name", "*.so*", "(", "-type", "f", "-o", "-type", "l", ")")
+	out, err := cmd.Output()
+	// find exits non-zero on unreadable directories, yet still lists the rest
+	if err != nil && len(out) == 0 {
+		return nil, fmt.Errorf("listing shared objects in %s: %w", image, err)
+	}
+	names := make(map[string]bool)
+	for _, p := range strings.Split(string(out), "\n") {
+		if p = strings.TrimSpace(p); p != "" {
+			names[path.Base(p)] = true
+		}
+	}
+	return names, nil
+}
+
+// checks that every soname a shared object needs (DT_NEEDED) is among available and, for a behavior plugin, that it exports a BT.CPP registration entrypoint; one problem is returned per failed check
+func CheckDependencies(file string, plugin bool, available map[string]bool) ([]string, error) {
+	f, err := elf.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var problems []string
+	needed, err := f.ImportedLibraries()
+	if err != nil {
+		return nil, fmt.Errorf("reading DT_NEEDED: %w", err)
+	}
+	for _, soname := range needed {
+		if !available[soname] {
+			problems = append(problems, fmt.Sprintf("needs %s, which is missing", soname))
+		}
+	}
+	if !plugin {
+		return problems, nil
+	}
+	symbols, err := f.DynamicSymbols()
+	if err != nil && err != elf.ErrNoSymbols {
+		return nil, fmt.Errorf("reading dynamic symbols: %w", err)
+	}
+	exported := slices.ContainsFunc(symbols, func(s elf.Symbol) bool {
+		bind := elf.ST_BIND(s.Info)
+		return slices.Contains(pluginEntrypoints, s.Name) && s.Section != elf.SHN_UNDEF && (bind == elf.STB_GLOBAL || bind == elf.STB_WEAK)
+	})
+	if !exported {
+		problems = append(problems, fmt.Sprintf("does not export %s", strings.Join(pluginEntrypoints, " or ")))
+	}
+	return problems, nil
+}
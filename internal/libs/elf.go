@@ -0,0 +1,142 @@
+package libs
+
+import (
+	"context"
+	"debug/elf"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"coral_cli/internal/docker"
+)
+
+// the OS and architecture an image was built for, as docker reports them (e.g. linux/arm64)
+type Platform struct {
+	OS           string `json:"os"`
+	Architecture string `json:"architecture"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+func (p Platform) String() string {
+	s := p.OS + "/" + p.Architecture
+	if p.Variant != "" {
+		s += "/" + p.Variant
+	}
+	return s
+}
+
+// returned (wrapped) by CheckSharedObject when a file cannot be loaded on the given platform
+var ErrIncompatibleLibrary = errors.New("incompatible library")
+
+// the ELF machine, class and byte order each docker architecture loads
+var elfTargets = map[string]struct {
+	machine elf.Machine
+	class   elf.Class
+	order   elf.Data
+}{
+	"amd64":    {elf.EM_X86_64, elf.ELFCLASS64, elf.ELFDATA2LSB},
+	"386":      {elf.EM_386, elf.ELFCLASS32, elf.ELFDATA2LSB},
+	"arm64":    {elf.EM_AARCH64, elf.ELFCLASS64, elf.ELFDATA2LSB},
+	"arm":      {elf.EM_ARM, elf.ELFCLASS32, elf.ELFDATA2LSB},
+	"ppc64le":  {elf.EM_PPC64, elf.ELFCLASS64, elf.ELFDATA2LSB},
+	"s390x":    {elf.EM_S390, elf.ELFCLASS64, elf.ELFDATA2MSB},
+	"riscv64":  {elf.EM_RISCV, elf.ELFCLASS64, elf.ELFDATA2LSB},
+	"mips64le": {elf.EM_MIPS, elf.ELFCLASS64, elf.ELFDATA2LSB},
+}
+
+// returns the platform of the image a created or running container was started from
+func GetContainerPlatform(ctx context.Context, containerID string) (Platform, error) {
+	image, err := containerImage(ctx, containerID)
+	if err != nil {
+		return Platform{}, err
+	}
+	return GetImagePlatform(ctx, image)
+}
+
+// returns the ID of the image a container was created from
+func containerImage(ctx context.Context, containerID string) (string, error) {
+	out, err := docker.CommandContext(ctx, docker.Query, "inspect", "--format", "{{.Image}}", containerID).Output()
+	if err != nil {
+		return "", fmt.Errorf("inspecting %s: %w", shortContainerID(containerID), err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// returns the platform of a local image
+func GetImagePlatform(ctx context.Context, image string) (Platform, error) {
+	out, err := docker.CommandContext(ctx, docker.Query, "image", "inspect", "--format", "{{.Os}} {{.Architecture}} {{.Variant}}", image).Output()
+	if err != nil {
+		return Platform{}, fmt.Errorf("inspecting platform of %s: %w", image, err)
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) < 2 {
+		return Platform{}, fmt.Errorf("inspecting platform of %s: unexpected output %q", image, out)
+	}
+	p := Platform{OS: fields[0], Architecture: fields[1]}
+	if len(fields) > 2 {
+		p.Variant = fields[2]
+	}
+	return p, nil
+}
+
+// reports whether a library file name is that of a shared object, e.g. libfoo.so or libfoo.so.1.2
+func IsSharedObjectName(name string) bool {
+	return strings.HasSuffix(name, ".so") || strings.Contains(name, ".so.")
+}
+
+// checks that the file at path is an ELF shared object (ET_DYN) whose machine, class and byte order match platform; an unknown architecture is not checked beyond the file type
+func CheckSharedObject(path string, platform Platform) error {
+	f, err := elf.Open(path)
+	if err != nil {
+		var formatErr *elf.FormatError
+		if errors.As(err, &formatErr) {
+			return fmt.Errorf("%w: not an ELF file", ErrIncompatibleLibrary)
+		}
+		return err
+	}
+	defer f.Close()
+
+	if f.Type != elf.ET_DYN {
+		return fmt.Errorf("%w: not a shared object (%s)", ErrIncompatibleLibrary, f.Type)
+	}
+	if platform.OS != "" && platform.OS != "linux" {
+		return fmt.Errorf("%w: ELF library cannot be loaded on %s", ErrIncompatibleLibrary, platform)
+	}
+	target, ok := elfTargets[platform.Architecture]
+	if !ok {
+		return nil
+	}
+	if f.Machine != target.machine || f.Class != target.class || f.Data != target.order {
+		return fmt.Errorf("%w: built for %s (%s, %s), not %s", ErrIncompatibleLibrary, f.Machine, f.Class, f.Data, platform)
+	}
+	return nil
+}
+
+// checks every shared object under dir against platform, returning the reason for each one that fails keyed by its path relative to dir
+func CheckSharedObjects(dir string, platform Platform) (map[string]string, error) {
+	problems := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// symlinked sonames are checked through the file they point to
+		if d.IsDir() || d.Type()&fs.ModeSymlink != 0 || !IsSharedObjectName(d.Name()) {
+			return nil
+		}
+		if err := CheckSharedObject(path, platform); err != nil {
+			rel, _ := filepath.Rel(dir, path)
+			if !errors.Is(err, ErrIncompatibleLibrary) {
+				return fmt.Errorf("reading %s: %w", rel, err)
+			}
+			problems[rel] = err.Error()
+		}
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return problems, nil
+	}
+	return problems, err
+}
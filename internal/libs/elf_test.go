@@ -0,0 +1,97 @@
+package libs
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writes a file holding only an ELF header, which is all CheckSharedObject reads
+func writeELF(t *testing.T, class elf.Class, data elf.Data, typ elf.Type, machine elf.Machine) string {
+	t.Helper()
+	var order binary.ByteOrder = binary.LittleEndian
+	if data == elf.ELFDATA2MSB {
+		order = binary.BigEndian
+	}
+	var ident [elf.EI_NIDENT]byte
+	copy(ident[:], elf.ELFMAG)
+	ident[elf.EI_CLASS] = byte(class)
+	ident[elf.EI_DATA] = byte(data)
+	ident[elf.EI_VERSION] = byte(elf.EV_CURRENT)
+
+	var buf bytes.Buffer
+	var header any
+	if class == elf.ELFCLASS64 {
+		header = elf.Header64{Ident: ident, Type: uint16(typ), Machine: uint16(machine), Version: uint32(elf.EV_CURRENT), Ehsize: 64}
+	} else {
+		header = elf.Header32{Ident: ident, Type: uint16(typ), Machine: uint16(machine), Version: uint32(elf.EV_CURRENT), Ehsize: 52}
+	}
+	if err := binary.Write(&buf, order, header); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "libtest.so")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCheckSharedObject(t *testing.T) {
+	amd64 := Platform{OS: "linux", Architecture: "amd64"}
+	tests := []struct {
+		name     string
+		class    elf.Class
+		data     elf.Data
+		typ      elf.Type
+		machine  elf.Machine
+		platform Platform
+		ok       bool
+	}{
+		{name: "matching amd64", class: elf.ELFCLASS64, data: elf.ELFDATA2LSB, typ: elf.ET_DYN, machine: elf.EM_X86_64, platform: amd64, ok: true},
+		{name: "matching arm64", class: elf.ELFCLASS64, data: elf.ELFDATA2LSB, typ: elf.ET_DYN, machine: elf.EM_AARCH64, platform: Platform{OS: "linux", Architecture: "arm64"}, ok: true},
+		{name: "matching 32-bit arm", class: elf.ELFCLASS32, data: elf.ELFDATA2LSB, typ: elf.ET_DYN, machine: elf.EM_ARM, platform: Platform{OS: "linux", Architecture: "arm", Variant: "v7"}, ok: true},
+		{name: "matching big-endian s390x", class: elf.ELFCLASS64, data: elf.ELFDATA2MSB, typ: elf.ET_DYN, machine: elf.EM_S390, platform: Platform{OS: "linux", Architecture: "s390x"}, ok: true},
+		{name: "machine mismatch", class: elf.ELFCLASS64, data: elf.ELFDATA2LSB, typ: elf.ET_DYN, machine: elf.EM_AARCH64, platform: amd64},
+		{name: "class mismatch", class: elf.ELFCLASS32, data: elf.ELFDATA2LSB, typ: elf.ET_DYN, machine: elf.EM_X86_64, platform: amd64},
+		{name: "32-bit x86 on amd64", class: elf.ELFCLASS32, data: elf.ELFDATA2LSB, typ: elf.ET_DYN, machine: elf.EM_386, platform: amd64},
+		{name: "byte order mismatch", class: elf.ELFCLASS64, data: elf.ELFDATA2MSB, typ: elf.ET_DYN, machine: elf.EM_PPC64, platform: Platform{OS: "linux", Architecture: "ppc64le"}},
+		{name: "executable, not shared object", class: elf.ELFCLASS64, data: elf.ELFDATA2LSB, typ: elf.ET_EXEC, machine: elf.EM_X86_64, platform: amd64},
+		{name: "relocatable object", class: elf.ELFCLASS64, data: elf.ELFDATA2LSB, typ: elf.ET_REL, machine: elf.EM_X86_64, platform: amd64},
+		{name: "non-linux platform", class: elf.ELFCLASS64, data: elf.ELFDATA2LSB, typ: elf.ET_DYN, machine: elf.EM_X86_64, platform: Platform{OS: "windows", Architecture: "amd64"}},
+		{name: "unknown architecture is not checked", class: elf.ELFCLASS64, data: elf.ELFDATA2LSB, typ: elf.ET_DYN, machine: elf.EM_X86_64, platform: Platform{OS: "linux", Architecture: "loong64"}, ok: true},
+		{name: "zero platform checks only the type", class: elf.ELFCLASS32, data: elf.ELFDATA2MSB, typ: elf.ET_DYN, machine: elf.EM_MIPS, ok: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckSharedObject(writeELF(t, tt.class, tt.data, tt.typ, tt.machine), tt.platform)
+			switch {
+			case tt.ok && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case !tt.ok && !errors.Is(err, ErrIncompatibleLibrary):
+				t.Errorf("got %v, want %v", err, ErrIncompatibleLibrary)
+			case !tt.ok && strings.Contains(err.Error(), "not an ELF file"):
+				t.Errorf("header was not parsed: %v", err)
+			}
+		})
+	}
+}
+
+func TestCheckSharedObjectNotELF(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "libscript.so")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckSharedObject(script, Platform{OS: "linux", Architecture: "amd64"}); !errors.Is(err, ErrIncompatibleLibrary) {
+		t.Errorf("non-ELF file: got %v, want %v", err, ErrIncompatibleLibrary)
+	}
+	// a file that cannot be read is an error, not an incompatibility
+	if err := CheckSharedObject(filepath.Join(dir, "missing.so"), Platform{}); err == nil || errors.Is(err, ErrIncompatibleLibrary) {
+		t.Errorf("missing file: got %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+package libs
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// the subdirectories every payload may export, of which only the top-level files are injected
+var libraryDirs = []string{"behaviors", "interfaces"}
+
+// a file a payload exports for injection
+type exportedFile struct {
+	subDir  string // slash-separated directory relative to CORAL_EXPORT_LIB, e.g. behaviors or trees/arm
+	name    string
+	path    string // path in the staging directory
+	mtime   time.Time
+	symlink bool
+}
+
+// lists the files a payload's staging directory exports: the top-level files of behaviors/ and interfaces/, and every file below the subtrees its manifest exports
+func exportedFiles(stagingDir string) ([]exportedFile, error) {
+	var files []exportedFile
+	for _, subDir := range libraryDirs {
+		entries, err := os.ReadDir(filepath.Join(stagingDir, subDir))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", subDir, err)
+		}
+		for _, e := range entries {
+			p := filepath.Join(stagingDir, subDir, e.Name())
+			symlink := e.Type()&fs.ModeSymlink != 0
+			info, err := e.Info()
+			if symlink {
+				info, err = os.Stat(p)
+			}
+			if err != nil || info.IsDir() {
+				continue
+			}
+			files = append(files, exportedFile{
+				subDir:  subDir,
+				name:    e.Name(),
+				path:    p,
+				mtime:   info.ModTime(),
+				symlink: symlink,
+			})
+		}
+	}
+
+	manifest, err := ReadManifest(stagingDir)
+	if err != nil || manifest == nil {
+		return files, err
+	}
+	for _, export := range manifest.Exports {
+		dir := filepath.Join(stagingDir, filepath.FromSlash(export))
+		if files, err = walkExport(files, dir, export, nil); err != nil {
+			return nil, fmt.Errorf("reading exported %s: %w", export, err)
+		}
+	}
+	return files, nil
+}
+
+// appends every file below dir, whose path relative to CORAL_EXPORT_LIB is subDir, to files. Symlinks to directories are followed and their files kept below the link's path; ancestors holds the resolved directories being walked, so a link back into one of them is refused rather than followed forever
+func walkExport(files []exportedFile, dir, subDir string, ancestors []string) ([]exportedFile, error) {
+	real, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return nil, err
+	}
+	if slices.Contains(ancestors, real) {
+		return nil, fmt.Errorf("%s links back to %s", subDir, real)
+	}
+	ancestors = append(ancestors, real)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		p := filepath.Join(dir, e.Name())
+		symlink := e.Type()&fs.ModeSymlink != 0
+		info, err := e.Info()
+		if symlink {
+			info, err = os.Stat(p)
+		}
+		if err != nil {
+			continue
+		}
+		if info.IsDir() {
+			if files, err = walkExport(files, p, path.Join(subDir, e.Name()), ancestors); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		files = append(files, exportedFile{
+			subDir:  subDir,
+			name:    e.Name(),
+			path:    p,
+			mtime:   info.ModTime(),
+			symlink: symlink,
+		})
+	}
+	return files, nil
+}
+
+// rejects an exported subtree that is not a relative directory inside CORAL_EXPORT_LIB, or that overlaps behaviors/, interfaces/ or another export
+func validateExports(exports []string) error {
+	for i, export := range exports {
+		if export == "" || path.IsAbs(export) || path.Clean(export) != export || export == "." || export == ".." || strings.HasPrefix(export, "../") {
+			return fmt.Errorf("export %q must be a clean relative path inside CORAL_EXPORT_LIB", export)
+		}
+		for _, other := range slices.Concat(libraryDirs, exports[:i]) {
+			if export == other || strings.HasPrefix(export, other+"/") || strings.HasPrefix(other, export+"/") {
+				return fmt.Errorf("export %q overlaps %s", export, other)
+			}
+		}
+	}
+	return nil
+}
@@ -1,22 +1,25 @@
 package libs
 
 import (
+	"archive/tar"
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
-	"syscall"
 
+	"coral_cli/internal/docker"
 	"coral_cli/internal/logging"
 
 	"github.com/google/uuid"
 )
 
 // probes an image by creating a stopped container, reading CORAL_EXPORT_LIB from its environment, copying the library tree to staging/<imageID>/ under lib, then removing the probe container. docker.yaml (if present) stays inside the staging directory alongside the behavior/interface libraries; the caller is responsible for recording the extraction in the registry
-func ExtractLibraries(image, name, lib string) (stagingDir string, imageID string, err error) {
-	imageID, err = GetImageID(image)
+func ExtractLibraries(ctx context.Context, image, name, lib string) (stagingDir string, imageID string, err error) {
+	imageID, err = GetImageID(ctx, image)
 	if err != nil {
 		return "", "", fmt.Errorf("getting image ID for %s: %w", image, err)
 	}
@@ -30,8 +33,7 @@ func ExtractLibraries(image, name, lib string) (stagingDir string, imageID strin
 
 	uid := uuid.New()
 	probeName := fmt.Sprintf("coral-probe-%x", uid[:4])
-	createCmd := exec.Command("docker", "create", "--name", probeName, image)
-	createCmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	createCmd := docker.CommandContext(ctx, docker.Copy, "create", "--name", probeName, image)
 	out, err := createCmd.Output()
 	if err != nil {
 		return "", "", fmt.Errorf("creating probe container for %s: %w", image, err)
@@ -39,12 +41,12 @@ func ExtractLibraries(image, name, lib string) (stagingDir string, imageID strin
 	containerID := strings.TrimSpace(string(out))
 
 	defer func() {
-		rmCmd := exec.Command("docker", "rm", containerID)
-		rmCmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		// not bound to ctx: the probe must be removed even when extraction was interrupted
+		rmCmd := docker.CommandContext(context.Background(), docker.Query, "rm", containerID)
 		rmCmd.Run() // best-effort
 	}()
 
-	libPath, err := readContainerEnv(containerID, "CORAL_EXPORT_LIB")
+	libPath, err := ReadContainerEnv(ctx, containerID, "CORAL_EXPORT_LIB")
 	if err != nil {
 		return "", "", fmt.Errorf("reading CORAL_EXPORT_LIB from %s: %w", image, err)
 	}
@@ -57,27 +59,23 @@ func ExtractLibraries(image, name, lib string) (stagingDir string, imageID strin
 	}
 
 	// docker cp streams through the socket — no host-path translation needed even when CORAL itself is running inside a container
-	cpCmd := exec.Command("docker", "cp",
+	cpCmd := docker.CommandContext(ctx, docker.Copy, "cp",
 		fmt.Sprintf("%s:%s/.", containerID, libPath), // trailing "/." = copy contents
 		stagingDir)
-	cpCmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
 	if out, err := cpCmd.CombinedOutput(); err != nil {
 		os.RemoveAll(stagingDir)
 		return "", "", fmt.Errorf("copying from probe container for %s: %w\n%s", image, err, out)
 	}
 
-	fmt.Println(logging.Info(fmt.Sprintf(
-		"Extracted libraries from %s for %s", image, logging.BoldMagenta(name),
-	)))
+	logging.Infof("Extracted libraries from %s for %s", image, logging.BoldMagenta(name))
 	return stagingDir, imageID, nil
 }
 
 // inspects a stopped container and returns the value of the named environment variable, or "" if not set
-func readContainerEnv(containerID, varName string) (string, error) {
-	cmd := exec.Command("docker", "inspect",
+func ReadContainerEnv(ctx context.Context, containerID, varName string) (string, error) {
+	cmd := docker.CommandContext(ctx, docker.Query, "inspect",
 		"--format", "{{json .Config.Env}}",
 		containerID)
-	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
 	out, err := cmd.Output()
 	if err != nil {
 		return "", fmt.Errorf("inspecting container env: %w", err)
@@ -95,19 +93,36 @@ func readContainerEnv(containerID, varName string) (string, error) {
 	return "", nil
 }
 
+// returns the contents of a regular file in a created or running container, which docker cp streams out as a tar archive; paths on the container's volumes can be read too
+func ReadContainerFile(ctx context.Context, containerID, path string) ([]byte, error) {
+	cmd := docker.CommandContext(ctx, docker.Copy, "cp", "-L", fmt.Sprintf("%s:%s", containerID, path), "-")
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("copying %s out of %s: %w", path, shortContainerID(containerID), err)
+	}
+	tr := tar.NewReader(bytes.NewReader(out))
+	hdr, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("reading archive of %s: %w", path, err)
+	}
+	if hdr.Typeflag != tar.TypeReg {
+		return nil, fmt.Errorf("%s is not a regular file", path)
+	}
+	return io.ReadAll(tr)
+}
+
 // returns the labels on the named image; the image must already be local
-func GetImageLabels(image string) (map[string]string, error) {
-	return inspectLabels(image)
+func GetImageLabels(ctx context.Context, image string) (map[string]string, error) {
+	return inspectLabels(ctx, image)
 }
 
 // returns the labels on a created or running container; container labels include all image labels
-func GetContainerLabels(containerID string) (map[string]string, error) {
-	return inspectLabels(containerID)
+func GetContainerLabels(ctx context.Context, containerID string) (map[string]string, error) {
+	return inspectLabels(ctx, containerID)
 }
 
-func inspectLabels(dockerObject string) (map[string]string, error) {
-	cmd := exec.Command("docker", "inspect", "--format", "{{json .Config.Labels}}", dockerObject)
-	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+func inspectLabels(ctx context.Context, dockerObject string) (map[string]string, error) {
+	cmd := docker.CommandContext(ctx, docker.Query, "inspect", "--format", "{{json .Config.Labels}}", dockerObject)
 	out, err := cmd.Output()
 	if err != nil {
 		return nil, fmt.Errorf("inspecting %s: %w", dockerObject, err)
@@ -123,14 +138,16 @@ func inspectLabels(dockerObject string) (map[string]string, error) {
 }
 
 // returns the full image digest for the named image, pulling it if absent
-func GetImageID(image string) (string, error) {
-	inspectCmd := exec.Command("docker", "inspect", "--format={{.Id}}", image)
-	inspectCmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+func GetImageID(ctx context.Context, image string) (string, error) {
+	inspectCmd := docker.CommandContext(ctx, docker.Query, "inspect", "--format={{.Id}}", image)
 	if out, err := inspectCmd.Output(); err == nil {
 		return strings.TrimSpace(string(out)), nil
 	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
 
-	fmt.Println(logging.Info(fmt.Sprintf("Image %s not found locally — pulling...", image)))
+	logging.Infof("Image %s not found locally — pulling...", image)
 	tmpFile, err := os.CreateTemp("", "compose-*.yml")
 	if err != nil {
 		return "", err
@@ -141,15 +158,14 @@ func GetImageID(image string) (string, error) {
 	}
 	tmpFile.Close()
 
-	pullCmd := exec.Command("docker", "compose", "-f", tmpFile.Name(), "pull")
-	pullCmd.Stdout = os.Stdout
-	pullCmd.Stderr = os.Stderr
+	pullCmd := docker.CommandContext(ctx, docker.Pull, "compose", "-f", tmpFile.Name(), "pull")
+	pullCmd.Stdout = logging.CommandOutput()
+	pullCmd.Stderr = logging.CommandErrors()
 	if err := pullCmd.Run(); err != nil {
 		return "", fmt.Errorf("pulling image %s: %w", image, err)
 	}
 
-	inspectCmd = exec.Command("docker", "inspect", "--format={{.Id}}", image)
-	inspectCmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	inspectCmd = docker.CommandContext(ctx, docker.Query, "inspect", "--format={{.Id}}", image)
 	out, err := inspectCmd.Output()
 	if err != nil {
 		return "", fmt.Errorf("inspecting image after pull: %w", err)
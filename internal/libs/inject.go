@@ -1,14 +1,21 @@
 package libs
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"os"
-	"os/exec"
+	"path"
 	"path/filepath"
-	"syscall"
+	"slices"
+	"strings"
 	"time"
 
+	"coral_cli/internal/docker"
 	"coral_cli/internal/logging"
 	"coral_cli/internal/registry"
 )
@@ -17,153 +24,295 @@ type libEntry struct {
 	srcPath   string
 	mtime     time.Time
 	payloadID string
+	priority  int
+	sha256    string
 }
 
-// merges behavior and interface libraries from all active staging directories into the executor container at the path given by CORAL_IMPORT_LIB in the container's environment; when two payloads provide a file with the same name in the same subdirectory (behaviors/ or interfaces/), the file with the newer modification timestamp wins and the losing entry is recorded as shadowed in the returned slice
-func InjectLibraries(containerID string, stagingDirs map[string]string) ([]registry.InjectedLib, error) {
-	if len(stagingDirs) == 0 {
-		return nil, nil
+// how InjectLibraries resolves two payloads that provide different files with the same name in the same subdirectory; files with identical content never conflict
+type ConflictPolicy string
+
+const (
+	ConflictPriority ConflictPolicy = "priority" // the payload with the highest priority wins, then the newer file, then the lower payload ID
+	ConflictNewest   ConflictPolicy = "newest"   // the newer file wins, then the lower payload ID
+	ConflictError    ConflictPolicy = "error"    // the injection fails
+)
+
+// reports whether p is a known policy
+func (p ConflictPolicy) Valid() bool {
+	switch p {
+	case ConflictPriority, ConflictNewest, ConflictError:
+		return true
 	}
+	return false
+}
+
+// returned (wrapped) by InjectLibraries and PlanLibraries when ConflictError is in force and two payloads provide differing files with the same name
+var ErrLibraryConflict = errors.New("library conflict")
+
+// a payload's staging directory and the priority its libraries carry under ConflictPriority
+type LibrarySource struct {
+	Dir      string
+	Priority int
+}
 
-	tmpDir, err := os.MkdirTemp("", "coral-inject-*")
+// merges behavior and interface libraries, along with the files of every subtree a payload's manifest exports, from all active staging directories into the executor container at the path given by CORAL_IMPORT_LIB in the container's environment, keeping their paths relative to CORAL_EXPORT_LIB; when two payloads provide a file with the same name in the same subdirectory (e.g. behaviors/ or trees/arm/), one is chosen by policy and the others are recorded as shadowed in the returned slice, along with the decision. Shared objects that cannot be loaded on the container's platform are not copied and are recorded as rejected; those copied are checked against the sonames of the container's image, with any problems recorded and reported. The injected files are streamed into the container as one tar archive, taken from cache when an executor was already given the same files, and the SHA-256 of each is recorded
+func InjectLibraries(ctx context.Context, containerID string, sources map[string]LibrarySource, policy ConflictPolicy, cache *ArchiveCache) ([]registry.InjectedLib, error) {
+	image, err := containerImage(ctx, containerID)
+	if err != nil {
+		return nil, err
+	}
+	platform, err := GetImagePlatform(ctx, image)
 	if err != nil {
-		return nil, fmt.Errorf("creating merge dir: %w", err)
+		return nil, fmt.Errorf("reading platform of %s: %w", shortContainerID(containerID), err)
+	}
+	winners, result, err := planLibraries(sources, policy, platform, true)
+	if err != nil || len(winners) == 0 {
+		return result, err
+	}
+	if cache == nil {
+		cache = NewArchiveCache()
+		defer cache.Close()
+	}
+	if err := checkDependencies(ctx, image, winners, result, cache); err != nil {
+		return nil, err
 	}
-	defer os.RemoveAll(tmpDir)
 
-	var result []registry.InjectedLib
+	importLib, err := ReadContainerEnv(ctx, containerID, "CORAL_IMPORT_LIB")
+	if err != nil {
+		return nil, fmt.Errorf("reading CORAL_IMPORT_LIB from %s: %w", shortContainerID(containerID), err)
+	}
+	if importLib == "" {
+		return nil, fmt.Errorf("executor container %s does not set CORAL_IMPORT_LIB", shortContainerID(containerID))
+	}
+	importLib = path.Clean(importLib)
+	if importLib == "/" || importLib == "." {
+		return nil, fmt.Errorf("executor container %s sets CORAL_IMPORT_LIB to %q, which is not a directory coral can import into", shortContainerID(containerID), importLib)
+	}
 
-	for _, subDir := range []string{"behaviors", "interfaces"} {
-		winners := make(map[string]libEntry) // filename → current best
-		var shadowedLibs []registry.InjectedLib
+	// the archive is rooted at the import directory's name and extracted into its parent, so docker creates the import directory if the image lacks it
+	archive, err := cache.archive(path.Base(importLib), winners)
+	if err != nil {
+		return nil, err
+	}
+	f, err := archive.open()
+	if err != nil {
+		return nil, fmt.Errorf("opening library archive: %w", err)
+	}
+	defer f.Close()
+	cpCmd := docker.CommandContext(ctx, docker.Copy, "cp", "-", fmt.Sprintf("%s:%s", containerID, path.Dir(importLib)))
+	cpCmd.Stdin = f
+	if out, err := cpCmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("injecting libraries into %s: %w\n%s", shortContainerID(containerID), err, out)
+	}
 
-		for payloadID, stagingDir := range stagingDirs {
-			srcSubDir := filepath.Join(stagingDir, subDir)
-			entries, err := os.ReadDir(srcSubDir)
-			if os.IsNotExist(err) {
-				continue
-			}
-			if err != nil {
-				return nil, fmt.Errorf("reading %s from %s: %w", subDir, stagingDir, err)
-			}
-			for _, e := range entries {
-				if e.IsDir() {
-					continue
-				}
-				info, err := e.Info()
-				if err != nil {
-					continue
-				}
-				name := e.Name()
-				entry := libEntry{
-					srcPath:   filepath.Join(srcSubDir, name),
-					mtime:     info.ModTime(),
-					payloadID: payloadID,
-				}
-				existing, exists := winners[name]
-				if !exists {
-					winners[name] = entry
-					continue
-				}
-				// newer modification timestamp wins; this cannot exist long-term but is simple and deterministic for now
-				if entry.mtime.After(existing.mtime) {
-					shadowedLibs = append(shadowedLibs, registry.InjectedLib{
-						PayloadID:  existing.payloadID,
-						LibName:    name,
-						SubDir:     subDir,
-						Shadowed:   true,
-						ShadowedBy: payloadID,
-					})
-					winners[name] = entry
-					fmt.Println(logging.Warning(fmt.Sprintf(
-						"Library conflict: %s/%s — %s (newer, %.0fs) overrides %s",
-						subDir, name, payloadID, entry.mtime.Sub(existing.mtime).Seconds(), existing.payloadID,
-					)))
-				} else {
-					shadowedLibs = append(shadowedLibs, registry.InjectedLib{
-						PayloadID:  payloadID,
-						LibName:    name,
-						SubDir:     subDir,
-						Shadowed:   true,
-						ShadowedBy: existing.payloadID,
-					})
-					fmt.Println(logging.Warning(fmt.Sprintf(
-						"Library conflict: %s/%s — %s (newer, %.0fs) overrides %s",
-						subDir, name, existing.payloadID, existing.mtime.Sub(entry.mtime).Seconds(), payloadID,
-					)))
-				}
-			}
+	for i, lib := range result {
+		if !lib.Shadowed && lib.Rejected == "" {
+			result[i].SHA256 = archive.checksums[lib.SubDir+"/"+lib.LibName]
 		}
+	}
+	return result, nil
+}
 
-		if len(winners) == 0 {
+// records in result the problems of every winning shared object: sonames it needs that neither the image nor another injected library provides, and, for behaviors, a missing plugin entrypoint. The image is only probed when a shared object wins, and its sonames are taken from cache when another executor of the same image was probed before. An image the sonames cannot be listed in is reported and not checked
+func checkDependencies(ctx context.Context, image string, winners map[string]map[string]libEntry, result []registry.InjectedLib, cache *ArchiveCache) error {
+	if !slices.ContainsFunc(result, func(lib registry.InjectedLib) bool {
+		return !lib.Shadowed && lib.Rejected == "" && IsSharedObjectName(lib.LibName)
+	}) {
+		return nil
+	}
+	imageObjects, err := cache.sharedObjects(ctx, image)
+	if err != nil {
+		if ctx.Err() != nil {
+			return err
+		}
+		logging.Warnf("Not checking library dependencies: %v", err)
+		return nil
+	}
+	available := maps.Clone(imageObjects)
+	for _, files := range winners {
+		for name := range files {
+			available[name] = true
+		}
+	}
+	for i, lib := range result {
+		if lib.Shadowed || lib.Rejected != "" || !IsSharedObjectName(lib.LibName) {
 			continue
 		}
+		problems, err := CheckDependencies(winners[lib.SubDir][lib.LibName].srcPath, lib.SubDir == "behaviors", available)
+		if err != nil {
+			return fmt.Errorf("checking %s/%s from %s: %w", lib.SubDir, lib.LibName, lib.PayloadID, err)
+		}
+		for _, problem := range problems {
+			logging.Failuref("Library %s/%s from %s %s", lib.SubDir, lib.LibName, lib.PayloadID, problem)
+		}
+		result[i].Problems = problems
+	}
+	return nil
+}
+
+// returns the libraries InjectLibraries would inject from sources into a container of the given platform, including shadowed and rejected ones, without copying anything; a zero platform accepts every file
+func PlanLibraries(sources map[string]LibrarySource, policy ConflictPolicy, platform Platform) ([]registry.InjectedLib, error) {
+	_, result, err := planLibraries(sources, policy, platform, false)
+	return result, err
+}
 
-		// copy winning files to the merge directory
-		destSubDir := filepath.Join(tmpDir, subDir)
-		if err := os.MkdirAll(destSubDir, 0755); err != nil {
-			return nil, err
+// resolves which file wins for every library name in each subdirectory, including those of the payloads' exported subtrees; winners maps subdirectory → filename → winning file. Shared objects that cannot be loaded on platform are rejected before conflicts are resolved. Conflicts and rejections are logged when report is set
+func planLibraries(sources map[string]LibrarySource, policy ConflictPolicy, platform Platform, report bool) (map[string]map[string]libEntry, []registry.InjectedLib, error) {
+	var result []registry.InjectedLib
+	candidatesBySubDir := make(map[string]map[string][]libEntry) // subdirectory → filename → every payload's file
+
+	// payloads are visited in a fixed order so every tie is broken the same way
+	for _, payloadID := range slices.Sorted(maps.Keys(sources)) {
+		source := sources[payloadID]
+		files, err := exportedFiles(source.Dir)
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading libraries of %s: %w", payloadID, err)
 		}
-		for name, entry := range winners {
-			if err := copyFile(entry.srcPath, filepath.Join(destSubDir, name)); err != nil {
-				return nil, fmt.Errorf("merging %s/%s: %w", subDir, name, err)
+		for _, f := range files {
+			if platform != (Platform{}) && IsSharedObjectName(f.name) && !f.symlink {
+				if err := CheckSharedObject(f.path, platform); err != nil {
+					if !errors.Is(err, ErrIncompatibleLibrary) {
+						return nil, nil, fmt.Errorf("reading %s/%s from %s: %w", f.subDir, f.name, payloadID, err)
+					}
+					if report {
+						logging.Failuref("Not injecting %s/%s from %s: %v", f.subDir, f.name, payloadID, err)
+					}
+					result = append(result, registry.InjectedLib{PayloadID: payloadID, LibName: f.name, SubDir: f.subDir, Rejected: err.Error()})
+					continue
+				}
+			}
+			if candidatesBySubDir[f.subDir] == nil {
+				candidatesBySubDir[f.subDir] = make(map[string][]libEntry)
 			}
-			result = append(result, registry.InjectedLib{
-				PayloadID: entry.payloadID,
-				LibName:   name,
-				SubDir:    subDir,
+			candidatesBySubDir[f.subDir][f.name] = append(candidatesBySubDir[f.subDir][f.name], libEntry{
+				srcPath:   f.path,
+				mtime:     f.mtime,
+				payloadID: payloadID,
+				priority:  source.Priority,
 			})
 		}
-		result = append(result, shadowedLibs...)
 	}
 
-	if len(result) == 0 {
-		return result, nil
+	winnersBySubDir := make(map[string]map[string]libEntry, len(candidatesBySubDir))
+	for _, subDir := range slices.Sorted(maps.Keys(candidatesBySubDir)) {
+		candidates := candidatesBySubDir[subDir]
+		winners := make(map[string]libEntry, len(candidates))
+		for _, name := range slices.Sorted(maps.Keys(candidates)) {
+			entries := candidates[name]
+			if len(entries) == 1 {
+				winners[name] = entries[0]
+				result = append(result, registry.InjectedLib{PayloadID: entries[0].payloadID, LibName: name, SubDir: subDir})
+				continue
+			}
+			winner, records, err := resolveConflict(subDir, name, entries, policy, report)
+			if err != nil {
+				return nil, nil, err
+			}
+			winners[name] = winner
+			result = append(result, records...)
+		}
+		winnersBySubDir[subDir] = winners
 	}
+	return winnersBySubDir, result, nil
+}
 
-	importLib, err := readContainerEnv(containerID, "CORAL_IMPORT_LIB")
-	if err != nil {
-		return nil, fmt.Errorf("reading CORAL_IMPORT_LIB from %s: %w", shortContainerID(containerID), err)
-	}
-	if importLib == "" {
-		return nil, fmt.Errorf("executor container %s does not set CORAL_IMPORT_LIB", shortContainerID(containerID))
-	}
+// ranks the decisions resolveConflict records, a winner taking the strongest that applied against any other candidate
+var decisionStrength = map[string]int{"identical": 0, "order": 1, "newest": 2, "priority": 3}
 
-	// docker cp into the executor container
-	cpCmd := exec.Command("docker", "cp",
-		tmpDir+"/.",
-		fmt.Sprintf("%s:%s", containerID, importLib))
-	cpCmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
-	if out, err := cpCmd.CombinedOutput(); err != nil {
-		return nil, fmt.Errorf("injecting libraries into %s: %w\n%s", shortContainerID(containerID), err, out)
+// picks one of several payloads' files with the same name, returning it along with a record for every candidate: the winner first, then the shadowed ones, each carrying the decision that applied to it
+func resolveConflict(subDir, name string, entries []libEntry, policy ConflictPolicy, report bool) (libEntry, []registry.InjectedLib, error) {
+	for i := range entries {
+		sum, err := fileSHA256(entries[i].srcPath)
+		if err != nil {
+			return libEntry{}, nil, fmt.Errorf("hashing %s/%s from %s: %w", subDir, name, entries[i].payloadID, err)
+		}
+		entries[i].sha256 = sum
 	}
 
-	return result, nil
-}
+	// newer files first, ties keeping payload order; under ConflictPriority a higher priority comes before anything else
+	ordered := slices.Clone(entries)
+	slices.SortStableFunc(ordered, func(a, b libEntry) int {
+		if policy == ConflictPriority && a.priority != b.priority {
+			return b.priority - a.priority
+		}
+		return b.mtime.Compare(a.mtime)
+	})
+	winner := ordered[0]
 
-func copyFile(src, dst string) error {
-	in, err := os.Open(src)
-	if err != nil {
-		return err
+	var differing []string
+	for _, e := range ordered[1:] {
+		if e.sha256 != winner.sha256 {
+			differing = append(differing, e.payloadID)
+		}
+	}
+	if policy == ConflictError && len(differing) > 0 {
+		return libEntry{}, nil, fmt.Errorf("%w: %s/%s differs between %s and %s", ErrLibraryConflict, subDir, name, winner.payloadID, strings.Join(differing, ", "))
 	}
-	defer in.Close()
 
-	info, err := in.Stat()
-	if err != nil {
-		return err
+	winnerDecision := "identical"
+	var shadowed []registry.InjectedLib
+	for _, loser := range ordered[1:] {
+		decision := "identical"
+		switch {
+		case loser.sha256 == winner.sha256:
+			logging.Debugf("Library %s/%s is identical in %s and %s", subDir, name, winner.payloadID, loser.payloadID)
+		case policy == ConflictPriority && loser.priority != winner.priority:
+			decision = "priority"
+			if report {
+				logging.Warnf("Library conflict: %s/%s — %s (priority %d) overrides %s (priority %d)",
+					subDir, name, winner.payloadID, winner.priority, loser.payloadID, loser.priority)
+			}
+		case !loser.mtime.Equal(winner.mtime):
+			decision = "newest"
+			if report {
+				logging.Warnf("Library conflict: %s/%s — %s (newer, %.0fs) overrides %s",
+					subDir, name, winner.payloadID, winner.mtime.Sub(loser.mtime).Seconds(), loser.payloadID)
+			}
+		default:
+			// nothing tells the files apart, so payloads keep their order
+			decision = "order"
+			if report {
+				logging.Warnf("Library conflict: %s/%s — %s overrides %s, which has the same priority and modification time, by payload order",
+					subDir, name, winner.payloadID, loser.payloadID)
+			}
+		}
+		// the winner is described by the strongest reason it beat any other candidate
+		if decisionStrength[decision] > decisionStrength[winnerDecision] {
+			winnerDecision = decision
+		}
+		shadowed = append(shadowed, registry.InjectedLib{
+			PayloadID:  loser.payloadID,
+			LibName:    name,
+			SubDir:     subDir,
+			Shadowed:   true,
+			ShadowedBy: winner.payloadID,
+			Decision:   decision,
+		})
 	}
+	records := append([]registry.InjectedLib{{PayloadID: winner.payloadID, LibName: name, SubDir: subDir, Decision: winnerDecision}}, shadowed...)
+	return winner, records, nil
+}
 
-	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode())
-	if err != nil {
-		return err
+// returns the SHA-256 of the file the payload in sources that provides lib would inject, as InjectLibraries records it
+func LibrarySHA256(sources map[string]LibrarySource, lib registry.InjectedLib) (string, error) {
+	source, ok := sources[lib.PayloadID]
+	if !ok {
+		return "", fmt.Errorf("payload %s is not among the sources", lib.PayloadID)
 	}
-	defer out.Close()
+	return fileSHA256(filepath.Join(source.Dir, filepath.FromSlash(lib.SubDir), lib.LibName))
+}
 
-	if _, err := io.Copy(out, in); err != nil {
-		return err
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
 	}
-	// preserve modification timestamp so conflict resolution is stable on re-injection
-	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
 func shortContainerID(id string) string {
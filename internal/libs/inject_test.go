@@ -0,0 +1,150 @@
+package libs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestResolveConflict(t *testing.T) {
+	now := time.Now()
+	type candidate struct {
+		payload  string
+		content  string
+		priority int
+		age      time.Duration // how long before now the file was written
+	}
+	tests := []struct {
+		name       string
+		policy     ConflictPolicy
+		candidates []candidate
+		winner     string
+		decisions  map[string]string // payload → decision recorded for it
+		err        error
+	}{
+		{
+			name:   "higher priority beats newer file",
+			policy: ConflictPriority,
+			candidates: []candidate{
+				{payload: "a", content: "old", priority: 10, age: time.Hour},
+				{payload: "b", content: "new", priority: 0},
+			},
+			winner:    "a",
+			decisions: map[string]string{"a": "priority", "b": "priority"},
+		},
+		{
+			name:   "equal priority falls back to the newer file",
+			policy: ConflictPriority,
+			candidates: []candidate{
+				{payload: "a", content: "old", age: time.Hour},
+				{payload: "b", content: "new"},
+			},
+			winner:    "b",
+			decisions: map[string]string{"a": "newest", "b": "newest"},
+		},
+		{
+			name:   "full tie keeps payload order",
+			policy: ConflictPriority,
+			candidates: []candidate{
+				{payload: "a", content: "one"},
+				{payload: "b", content: "two"},
+			},
+			winner:    "a",
+			decisions: map[string]string{"a": "order", "b": "order"},
+		},
+		{
+			name:   "newest ignores priority",
+			policy: ConflictNewest,
+			candidates: []candidate{
+				{payload: "a", content: "old", priority: 10, age: time.Hour},
+				{payload: "b", content: "new"},
+			},
+			winner:    "b",
+			decisions: map[string]string{"a": "newest", "b": "newest"},
+		},
+		{
+			name:   "newest tie keeps payload order despite priority",
+			policy: ConflictNewest,
+			candidates: []candidate{
+				{payload: "a", content: "one"},
+				{payload: "b", content: "two", priority: 10},
+			},
+			winner:    "a",
+			decisions: map[string]string{"a": "order", "b": "order"},
+		},
+		{
+			name:   "identical content never conflicts",
+			policy: ConflictError,
+			candidates: []candidate{
+				{payload: "a", content: "same", age: time.Hour},
+				{payload: "b", content: "same"},
+			},
+			winner:    "b",
+			decisions: map[string]string{"a": "identical", "b": "identical"},
+		},
+		{
+			name:   "differing content fails under error",
+			policy: ConflictError,
+			candidates: []candidate{
+				{payload: "a", content: "one"},
+				{payload: "b", content: "two"},
+			},
+			err: ErrLibraryConflict,
+		},
+		{
+			name:   "winner takes the strongest decision",
+			policy: ConflictPriority,
+			candidates: []candidate{
+				{payload: "a", content: "win", priority: 5},
+				{payload: "b", content: "win", priority: 5, age: time.Hour},
+				{payload: "c", content: "lose", priority: 1},
+			},
+			winner:    "a",
+			decisions: map[string]string{"a": "priority", "b": "identical", "c": "priority"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			var entries []libEntry
+			for _, c := range tt.candidates {
+				path := filepath.Join(dir, c.payload)
+				if err := os.WriteFile(path, []byte(c.content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				entries = append(entries, libEntry{srcPath: path, mtime: now.Add(-c.age), payloadID: c.payload, priority: c.priority})
+			}
+
+			winner, records, err := resolveConflict("behaviors", "libfoo.so", entries, tt.policy, false)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("got error %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if winner.payloadID != tt.winner {
+				t.Errorf("winner %s, want %s", winner.payloadID, tt.winner)
+			}
+			if len(records) != len(tt.candidates) {
+				t.Fatalf("got %d records, want %d", len(records), len(tt.candidates))
+			}
+			if records[0].PayloadID != tt.winner || records[0].Shadowed {
+				t.Errorf("first record %+v is not the winner %s", records[0], tt.winner)
+			}
+			for _, r := range records {
+				if r.Decision != tt.decisions[r.PayloadID] {
+					t.Errorf("%s has decision %q, want %q", r.PayloadID, r.Decision, tt.decisions[r.PayloadID])
+				}
+				if r.PayloadID != tt.winner && (!r.Shadowed || r.ShadowedBy != tt.winner) {
+					t.Errorf("%s should be shadowed by %s: %+v", r.PayloadID, tt.winner, r)
+				}
+			}
+		})
+	}
+}
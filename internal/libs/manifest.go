@@ -0,0 +1,87 @@
+package libs
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+
+	"coral_cli/internal/bt"
+	"coral_cli/internal/registry"
+)
+
+// the manifest a payload may ship at the top of CORAL_EXPORT_LIB, next to behaviors/ and interfaces/
+const ManifestFile = "manifest.yaml"
+
+// what a payload declares about the libraries it exports
+type Manifest struct {
+	Nodes   []bt.NodeModel `yaml:"nodes"`   // the BT.CPP nodes its behaviors register
+	Exports []string       `yaml:"exports"` // further subdirectories (e.g. trees, params) merged recursively into executors, as slash-separated paths relative to CORAL_EXPORT_LIB
+}
+
+// reads the manifest in a payload's staging directory; a payload without one yields nil
+func ReadManifest(stagingDir string) (*Manifest, error) {
+	raw, err := os.ReadFile(filepath.Join(stagingDir, ManifestFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := yaml.Unmarshal(raw, &m); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", ManifestFile, err)
+	}
+	if err := validateExports(m.Exports); err != nil {
+		return nil, fmt.Errorf("%s: %w", ManifestFile, err)
+	}
+	seen := make(map[string]bool, len(m.Nodes))
+	for _, n := range m.Nodes {
+		if err := n.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", ManifestFile, err)
+		}
+		if seen[n.Name] {
+			return nil, fmt.Errorf("%s: node %s is declared more than once", ManifestFile, n.Name)
+		}
+		seen[n.Name] = true
+	}
+	return &m, nil
+}
+
+// returns the nodes registered by the behaviors in injected, as declared in the manifests of the payloads in sources. A node naming its library is provided only when that payload's file was injected; one that does not, when any of the payload's behaviors was. Payloads that injected behaviors without shipping a manifest are returned in unlisted, since their nodes cannot be known
+func InjectedNodes(sources map[string]LibrarySource, injected []registry.InjectedLib) (nodes map[string]bt.NodeModel, unlisted []string, err error) {
+	behaviors := make(map[string]map[string]bool) // payload → injected behavior file names
+	for _, lib := range injected {
+		if lib.SubDir != "behaviors" || lib.Shadowed || lib.Rejected != "" {
+			continue
+		}
+		if behaviors[lib.PayloadID] == nil {
+			behaviors[lib.PayloadID] = map[string]bool{}
+		}
+		behaviors[lib.PayloadID][lib.LibName] = true
+	}
+	nodes = make(map[string]bt.NodeModel)
+	for _, payloadID := range slices.Sorted(maps.Keys(behaviors)) {
+		source, ok := sources[payloadID]
+		if !ok {
+			continue
+		}
+		manifest, err := ReadManifest(source.Dir)
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading manifest of %s: %w", payloadID, err)
+		}
+		if manifest == nil {
+			unlisted = append(unlisted, payloadID)
+			continue
+		}
+		for _, n := range manifest.Nodes {
+			if n.Library == "" || behaviors[payloadID][n.Library] {
+				nodes[n.Name] = n
+			}
+		}
+	}
+	return nodes, unlisted, nil
+}
@@ -2,16 +2,15 @@ package logging
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
 	"io"
-	"os/exec"
-	"regexp"
+	"os"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/fatih/color"
-
-	"coral_cli/internal/util"
 )
 
 var (
@@ -19,6 +18,7 @@ var (
 	Green  = color.New(color.FgGreen).SprintFunc()
 	Yellow = color.New(color.FgYellow).SprintFunc()
 	Blue   = color.New(color.FgBlue).SprintFunc()
+	Cyan   = color.New(color.FgCyan).SprintFunc()
 )
 
 var (
@@ -28,6 +28,44 @@ var (
 	UnderlineMagenta = color.New(color.FgMagenta, color.Underline).SprintFunc()
 )
 
+// severity of a log record; records below the configured level are dropped
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// destination for log records and tailed container output; commands emitting structured results point it at stderr so stdout carries only the result
+var Output io.Writer = os.Stdout
+
+var (
+	mu         sync.Mutex
+	minLevel   = LevelInfo
+	jsonFormat bool
+)
+
+// sets the minimum level and record format ("text" or "json"); colour is disabled for json records, when noColor is set, or when the NO_COLOR environment variable is present
+func Configure(level Level, format string, noColor bool) error {
+	mu.Lock()
+	defer mu.Unlock()
+	switch format {
+	case "", "text":
+		jsonFormat = false
+	case "json":
+		jsonFormat = true
+	default:
+		return fmt.Errorf("invalid log format %q: must be one of text, json", format)
+	}
+	minLevel = level
+	if _, ok := os.LookupEnv("NO_COLOR"); ok || noColor || jsonFormat {
+		color.NoColor = true
+	}
+	return nil
+}
+
 func Info(msg string) string {
 	return Blue("[INFO] ") + msg
 }
@@ -44,123 +82,107 @@ func Failure(msg string) string {
 	return Red("[FAILURE] ") + msg
 }
 
-func TailLogs(containers []util.ContainerInfo, doneChan <-chan struct{}, tailAll bool) (<-chan struct{}, <-chan error) {
-	colors := []color.Attribute{
-		color.FgHiRed,
-		color.FgHiGreen,
-		color.FgHiYellow,
-		color.FgHiBlue,
-		color.FgHiMagenta,
-		color.FgHiCyan,
-		color.FgHiWhite,
-		color.FgHiBlack,
-	}
-	colorMap := make(map[string]*color.Color)
-	serviceCounts := make(map[string]int)
-	colorIndex := 0
-
-	printMu := &sync.Mutex{}
-	errCh := make(chan error, len(containers))
-	finished := make(chan struct{})
-
-	var wg sync.WaitGroup
-	for _, c := range containers {
-		key := c.Service
-		count := serviceCounts[key]
-		if count > 0 {
-			key = fmt.Sprintf("%s-%d", key, count)
-		}
-		serviceCounts[c.Service] = count + 1
+func Debug(msg string) string {
+	return Cyan("[DEBUG] ") + msg
+}
 
-		clr, exists := colorMap[key]
-		if !exists {
-			clr = color.New(colors[colorIndex%len(colors)]).Add(color.Bold)
-			colorMap[key] = clr
-			colorIndex++
-		}
+func Debugf(format string, args ...any) {
+	write(LevelDebug, "debug", Debug, fmt.Sprintf(format, args...))
+}
 
-		wg.Add(1)
-		go func(c util.ContainerInfo, clr *color.Color) {
-			defer wg.Done()
-
-			cmd := exec.Command("docker", "logs", "-f", c.ID)
-			if !tailAll {
-				cmd = exec.Command("docker", "logs", "-f", "--since", "0s", "--tail", "0", c.ID)
-			}
-			stdout, _ := cmd.StdoutPipe()
-			stderr, _ := cmd.StderrPipe()
-
-			if err := cmd.Start(); err != nil {
-				errCh <- fmt.Errorf("failed to start logs for %s: %w", c.Name, err)
-				return
-			}
-
-			printStream := func(r io.Reader) {
-				scanner := bufio.NewScanner(r)
-				for scanner.Scan() {
-					line := scanner.Text()
-					printMu.Lock()
-					clr.Printf("%-15s | ", key)
-					fmt.Println(line)
-					printMu.Unlock()
-				}
-			}
-
-			go printStream(stdout)
-			go printStream(stderr)
-
-			if err := cmd.Wait(); err != nil {
-				if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 130 {
-					return
-				}
-				errCh <- fmt.Errorf("logs exited for %s: %w", c.Name, err)
-			}
-		}(c, clr)
-	}
+func Infof(format string, args ...any) {
+	write(LevelInfo, "info", Info, fmt.Sprintf(format, args...))
+}
 
-	go func() {
-		wg.Wait()
-		close(finished)
-	}()
+// an info-level record marking the successful end of an operation
+func Successf(format string, args ...any) {
+	write(LevelInfo, "success", Success, fmt.Sprintf(format, args...))
+}
 
-	return finished, errCh
+func Warnf(format string, args ...any) {
+	write(LevelWarn, "warning", Warning, fmt.Sprintf(format, args...))
+}
+
+func Failuref(format string, args ...any) {
+	write(LevelError, "failure", Failure, fmt.Sprintf(format, args...))
+}
 
+type record struct {
+	Time      string `json:"time"`
+	Level     string `json:"level"`
+	Message   string `json:"msg"`
+	Container string `json:"container,omitempty"`
 }
 
-func GetContainerInfo(instanceName string, composePath string) ([]util.ContainerInfo, error) {
-	var containers []util.ContainerInfo
+func write(level Level, name string, decorate func(string) string, msg string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if level < minLevel {
+		return
+	}
+	if jsonFormat {
+		writeJSON(record{Time: time.Now().Format(time.RFC3339Nano), Level: name, Message: msg})
+		return
+	}
+	fmt.Fprintln(Output, decorate(msg))
+}
 
-	args := []string{"compose", "-p", instanceName, "-f", composePath, "ps", "-q"}
-	out, err := exec.Command("docker", args...).Output()
+func writeJSON(r record) {
+	data, err := json.Marshal(r)
 	if err != nil {
-		return containers, fmt.Errorf("failed to get container IDs: %w", err)
+		return
 	}
-	containerIDs := strings.Fields(string(out))
-	if len(containerIDs) == 0 {
-		return containers, fmt.Errorf("no containers found for instance %s", instanceName)
+	Output.Write(append(data, '\n'))
+}
+
+// writes one line of a tailed container's output, prefixed with its colour-coded service key; container output is not subject to the log level
+func ContainerLine(key string, clr *color.Color, line string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if jsonFormat {
+		writeJSON(record{Time: time.Now().Format(time.RFC3339Nano), Level: "output", Message: line, Container: key})
+		return
 	}
+	clr.Fprintf(Output, "%-15s | ", key)
+	fmt.Fprintln(Output, line)
+}
 
-	// inspect containers to get service names
-	prefix := instanceName + "-"
-	suffixRegex := regexp.MustCompile(`-\d+$`)
-	for _, id := range containerIDs {
-		nameOut, err := exec.Command("docker", "inspect", "-f", "{{.Name}}", id).Output()
-		if err != nil {
-			return containers, fmt.Errorf("failed to inspect container %s: %w", id, err)
-		}
-		fullName := strings.Trim(strings.TrimSpace(string(nameOut)), "/")
-		serviceName := fullName
-		if strings.HasPrefix(fullName, prefix) {
-			serviceName = fullName[len(prefix):]
-		}
-		serviceName = suffixRegex.ReplaceAllString(serviceName, "")
+// returns a writer for the stdout of a runtime command such as docker compose up: in text mode at info level it is the log output itself (so docker can keep its terminal progress display); otherwise each line becomes a debug record
+func CommandOutput() io.Writer {
+	mu.Lock()
+	defer mu.Unlock()
+	if !jsonFormat && minLevel <= LevelInfo {
+		return Output
+	}
+	return lineWriter{level: LevelDebug}
+}
 
-		containers = append(containers, util.ContainerInfo{
-			ID:      id,
-			Name:    fullName,
-			Service: serviceName,
-		})
+// returns a writer for the stderr of a runtime command: the terminal's stderr in text mode (docker reports both progress and errors there), otherwise each line becomes an info record so json logs stay parseable
+func CommandErrors() io.Writer {
+	mu.Lock()
+	defer mu.Unlock()
+	if !jsonFormat {
+		return os.Stderr
 	}
+	return lineWriter{level: LevelInfo}
+}
 
-	return containers, nil
+type lineWriter struct {
+	level Level
+}
+
+func (w lineWriter) Write(p []byte) (int, error) {
+	scanner := bufio.NewScanner(strings.NewReader(string(p)))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		if w.level == LevelDebug {
+			Debugf("%s", line)
+		} else {
+			Infof("%s", line)
+		}
+	}
+	return len(p), nil
 }
@@ -0,0 +1,82 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// selects how commands print their results; human-readable text is the default
+type Format string
+
+const (
+	Text Format = "text"
+	JSON Format = "json"
+	YAML Format = "yaml"
+)
+
+func ParseFormat(s string) (Format, error) {
+	switch Format(s) {
+	case "", Text:
+		return Text, nil
+	case JSON, YAML:
+		return Format(s), nil
+	}
+	return "", fmt.Errorf("invalid output format %q: must be one of text, json, yaml", s)
+}
+
+// reports whether results are emitted as documents rather than human text
+func (f Format) Structured() bool {
+	return f == JSON || f == YAML
+}
+
+// structured form of a failed command
+type ErrorResult struct {
+	Error ErrorDetail `json:"error"`
+}
+
+type ErrorDetail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// encodes v as a single JSON or YAML document; field names always come from json tags so both formats share one schema, and YAML documents are prefixed with a separator so several results can share one stream
+func Write(w io.Writer, f Format, v any) error {
+	switch f {
+	case JSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case YAML:
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		// JSON is valid YAML; decoding into a node keeps the field order of the JSON encoding
+		var node yaml.Node
+		if err := yaml.Unmarshal(raw, &node); err != nil {
+			return err
+		}
+		clearStyle(&node)
+		if _, err := io.WriteString(w, "---\n"); err != nil {
+			return err
+		}
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(&node); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+	return fmt.Errorf("output format %q is not structured", f)
+}
+
+// switches a decoded JSON tree from flow style to block style
+func clearStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		clearStyle(c)
+	}
+}
@@ -16,23 +16,31 @@ type ExtractionRecord struct {
 	InstanceIDs  []string `json:"instance_ids"`
 	BtcppVersion string   `json:"btcpp_version,omitempty"`
 	RosDistro    string   `json:"ros_distro,omitempty"`
+	Priority     int      `json:"priority,omitempty"` // wins library conflicts against lower priorities; from the coral.priority image label or the service's x-coral priority, as last recorded
+	Services     []string `json:"services,omitempty"` // compose services that ran the payload image, in any instance
+	Images       []string `json:"images,omitempty"`   // image references the payload was extracted from
 	// Deprecated: present only in old registry files; migrated to InstanceIDs on load.
 	InstanceID string `json:"instance_id,omitempty"`
 }
 
-// describes a single library file that was copied into an executor container
+// describes a single library or exported file that was copied into an executor container
 type InjectedLib struct {
-	PayloadID  string `json:"payload_id"`
-	LibName    string `json:"lib_name"`
-	SubDir     string `json:"sub_dir"`               // "behaviors" or "interfaces"
-	Shadowed   bool   `json:"shadowed,omitempty"`    // true if a newer-timestamp file from another payload won
-	ShadowedBy string `json:"shadowed_by,omitempty"` // payload ID that provided the winning file
+	PayloadID  string   `json:"payload_id"`
+	LibName    string   `json:"lib_name"`
+	SubDir     string   `json:"sub_dir"`               // "behaviors", "interfaces", or the file's directory within a subtree the payload exports, relative to CORAL_EXPORT_LIB (e.g. "trees/arm")
+	Shadowed   bool     `json:"shadowed,omitempty"`    // another payload's file won the conflict
+	ShadowedBy string   `json:"shadowed_by,omitempty"` // payload ID that provided the winning file
+	Decision   string   `json:"decision,omitempty"`    // how a name provided by several payloads was resolved: identical (same content), priority, newest or order (a full tie, broken by payload order)
+	Rejected   string   `json:"rejected,omitempty"`    // why the file was not injected, e.g. it was built for another architecture; a rejected file takes no part in conflicts
+	Problems   []string `json:"problems,omitempty"`    // why the injected file may fail to load in the executor: missing sonames, or no BT.CPP plugin entrypoint
+	SHA256     string   `json:"sha256,omitempty"`      // content hash of the injected file
 }
 
 // tracks which libraries were injected into an executor container
 type InjectionRecord struct {
 	ContainerID string        `json:"container_id"`
 	InstanceID  string        `json:"instance_id"`
+	Scope       string        `json:"scope,omitempty"` // which payloads were eligible: those of the instance, of its group, or every payload in the lib dir
 	Libs        []InjectedLib `json:"libs"`
 }
 
@@ -85,27 +93,40 @@ func Load(libPath string) (*Registry, error) {
 	return r, nil
 }
 
-// adds instanceID to the reference set for imageID; if the record does not yet exist, it is created with stagingDir/payloadID/versions; if it exists, those fields are left unchanged (the first extractor's values are canonical)
-func (r *Registry) RecordExtraction(imageID, stagingDir, payloadID, instanceID, btcppVersion, rosDistro string) error {
+// adds instanceID to the reference set of rec.ImageID; if no record exists yet, rec is stored as given, otherwise its staging dir, payload ID and versions are left unchanged (the first extractor's values are canonical) while its priority is replaced and rec's services and images are added
+func (r *Registry) RecordExtraction(rec ExtractionRecord, instanceID string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	rec, exists := r.data.Extractions[imageID]
-	if exists {
-		if containsStr(rec.InstanceIDs, instanceID) {
-			return nil // already recorded, no write needed
-		}
-		rec.InstanceIDs = append(rec.InstanceIDs, instanceID)
-		r.data.Extractions[imageID] = rec
+	existing, exists := r.data.Extractions[rec.ImageID]
+	if !exists {
+		rec.InstanceIDs = []string{instanceID}
+		r.data.Extractions[rec.ImageID] = rec
 		return r.save()
 	}
-	r.data.Extractions[imageID] = ExtractionRecord{
-		ImageID:      imageID,
-		StagingDir:   stagingDir,
-		PayloadID:    payloadID,
-		InstanceIDs:  []string{instanceID},
-		BtcppVersion: btcppVersion,
-		RosDistro:    rosDistro,
+	changed := false
+	for _, add := range []struct {
+		set    *[]string
+		values []string
+	}{
+		{&existing.InstanceIDs, []string{instanceID}},
+		{&existing.Services, rec.Services},
+		{&existing.Images, rec.Images},
+	} {
+		for _, v := range add.values {
+			if !containsStr(*add.set, v) {
+				*add.set = append(*add.set, v)
+				changed = true
+			}
+		}
+	}
+	if existing.Priority != rec.Priority {
+		existing.Priority = rec.Priority
+		changed = true
+	}
+	if !changed {
+		return nil // already recorded, no write needed
 	}
+	r.data.Extractions[rec.ImageID] = existing
 	return r.save()
 }
 
@@ -120,6 +141,17 @@ func (r *Registry) AllExtractions() map[string]ExtractionRecord {
 	return result
 }
 
+// returns a snapshot of all currently recorded injection records keyed by container ID
+func (r *Registry) AllInjections() map[string]InjectionRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result := make(map[string]InjectionRecord, len(r.data.Injections))
+	for containerID, rec := range r.data.Injections {
+		result[containerID] = rec
+	}
+	return result
+}
+
 // removes instanceID from the reference set for imageID; the staging directory path is returned (and should be deleted by the caller) only when the reference set becomes empty; an empty return value means other instances still hold the directory
 func (r *Registry) RemoveExtraction(imageID, instanceID string) (string, error) {
 	r.mu.Lock()
@@ -167,12 +199,13 @@ func (r *Registry) RemoveExtractionsForInstance(instanceID string) ([]string, er
 	return dirsToDelete, r.save()
 }
 
-func (r *Registry) RecordInjection(containerID, instanceID string, libs []InjectedLib) error {
+func (r *Registry) RecordInjection(containerID, instanceID, scope string, libs []InjectedLib) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	r.data.Injections[containerID] = InjectionRecord{
 		ContainerID: containerID,
 		InstanceID:  instanceID,
+		Scope:       scope,
 		Libs:        libs,
 	}
 	return r.save()
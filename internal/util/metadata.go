@@ -16,6 +16,25 @@ type InstanceMetadata struct {
 	Handle      string `json:"handle,omitempty"`
 	Group       string `json:"group,omitempty"`
 	Detached    bool   `json:"detached"`
+	// user labels given with coral launch --label, also set on every container of the instance
+	Labels map[string]string `json:"labels,omitempty"`
+	// which payloads' libraries are injected into the instance's executors: instance (the default when empty), group or global
+	InjectionScope string `json:"injection_scope,omitempty"`
+	// how libraries with the same name from different payloads are resolved: priority (the default when empty), newest or error
+	ConflictPolicy string `json:"conflict_policy,omitempty"`
+	// set when a shutdown was abandoned part-way; such instances are finished off by coral prune
+	Abandoned bool `json:"abandoned,omitempty"`
+	// set by coral estop or the critical-service interlock and cleared by coral resume
+	EStop *EStopRecord `json:"estop,omitempty"`
+}
+
+// the containers an emergency stop paused or killed, so they can be resumed
+type EStopRecord struct {
+	Mode       string              `json:"mode"` // pause or kill
+	At         string              `json:"at"`
+	Containers map[string][]string `json:"containers"`          // container IDs by profile
+	Interlock  bool                `json:"interlock,omitempty"` // set when the interlock halted executors because a critical service failed
+	Reason     string              `json:"reason,omitempty"`
 }
 
 type ContainerInfo struct {
@@ -52,6 +71,9 @@ func LoadAllMetadata() ([]InstanceMetadata, error) {
 	dir := filepath.Join(home, ".coral_cli", "instances")
 
 	files, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil // nothing has been launched yet
+	}
 	if err != nil {
 		return nil, fmt.Errorf("reading metadata dir: %w", err)
 	}
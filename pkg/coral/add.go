@@ -0,0 +1,188 @@
+package coral
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"coral_cli/internal/compose"
+	"coral_cli/internal/docker"
+	"coral_cli/internal/health"
+	"coral_cli/internal/libs"
+	"coral_cli/internal/logging"
+	"coral_cli/internal/registry"
+)
+
+// options for Launcher.Add
+type AddOptions struct {
+	ComposePath      string        // compose file with the drivers and skillsets to add; resolved like docker compose when empty
+	EnvFile          string        // optional .env file for compose substitutions; ./.env is used when empty and present
+	HealthTimeout    time.Duration // how long to wait for the added services to become healthy before re-injecting executors
+	ReloadSignal     string        // signal sent to each running executor after its libraries are re-injected (e.g. SIGHUP), so it can reload plugins; none when empty
+	SkipVersionCheck bool          // skip the coral.version compatibility check between the launcher and images
+}
+
+// what Launcher.Add started and re-injected
+type AddResult struct {
+	Instance string                   `json:"instance"`
+	Added    map[string][]string      `json:"added"`              // services started, by profile
+	Injected map[string][]InjectedLib `json:"injected,omitempty"` // libraries now injected into each running executor
+	Signaled []string                 `json:"signaled,omitempty"` // executors sent ReloadSignal
+}
+
+// starts the drivers and skillsets of another compose file within a running instance's compose project: their payloads are extracted and recorded against the instance, they are merged into its compose file and started, and once they are healthy the libraries of every running executor are injected again so it picks up the new payloads; executors without a running container, such as those merged by a mission, are left alone. Services already in the instance are reported as a conflict, and executors are rejected (use Reconcile to change them). Nothing is rolled back on failure
+func (l *Launcher) Add(ctx context.Context, inst *Instance, opts AddOptions) (*AddResult, error) {
+	cf, env, err := loadCompose(LaunchOptions{ComposePath: opts.ComposePath, EnvFile: opts.EnvFile})
+	if err != nil {
+		return nil, err
+	}
+	hostLibPath, err := hostLib(env)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkImagesLocal(ctx, cf, l.Version, opts.SkipVersionCheck); err != nil {
+		return nil, launchError(ctx, ErrCodeImage, fmt.Errorf("checking images: %w", err))
+	}
+
+	for name, svc := range cf.Services {
+		image, _ := svc["image"].(string)
+		labels, err := libs.GetImageLabels(ctx, image)
+		if err != nil {
+			return nil, launchError(ctx, ErrCodeImage, fmt.Errorf("reading labels for service %s: %w", name, err))
+		}
+		if labels["coral.profile"] == "executors" {
+			return nil, codedError(ErrCodeInvalidInput, fmt.Errorf("service %s is an executor: only drivers and skillsets can be added, use coral up to change executors", name))
+		}
+	}
+	reg, err := registry.Load(inst.LibPath)
+	if err != nil {
+		return nil, fmt.Errorf("loading registry: %w", err)
+	}
+	profilesMap, err := inst.mergeServices(ctx, cf, hostLibPath, reg)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &AddResult{Instance: inst.Name, Added: map[string][]string{}, Injected: map[string][]InjectedLib{}}
+	var started []string
+	for _, profile := range orderedProfiles(extractProfileNames(profilesMap)) {
+		services := profilesMap[profile]
+		slices.Sort(services)
+		logging.Infof("Starting %s (%d): %s", logging.BoldMagenta(profile), len(services), logging.BoldMagenta(fmt.Sprintf("%v", services)))
+		args := []string{"compose", "-p", inst.Name, "-f", inst.ComposeFile, "--profile", profile, "up", "-d", "--no-deps"}
+		cmd := docker.CommandContext(ctx, docker.Compose, append(args, services...)...)
+		cmd.Stdout = logging.CommandOutput()
+		cmd.Stderr = logging.CommandErrors()
+		if err := cmd.Run(); err != nil {
+			return result, launchError(ctx, ErrCodeStart, fmt.Errorf("starting %s: %w", profile, err))
+		}
+		result.Added[profile] = services
+		started = append(started, services...)
+	}
+
+	executors, err := runningExecutors(ctx, inst.Name, inst.profilesMap["executors"])
+	if err != nil {
+		return result, launchError(ctx, ErrCodeStart, err)
+	}
+	if len(executors) > 0 {
+		logging.Infof("Waiting for added services to become healthy...")
+		if err := health.WaitForHealthy(ctx, inst.Name, started, opts.HealthTimeout); err != nil {
+			if ctx.Err() != nil {
+				return result, launchError(ctx, ErrCodeInterrupted, err)
+			}
+			logging.Warnf("Health gate timed out: %v — proceeding anyway", err)
+		}
+	}
+	cache := libs.NewArchiveCache()
+	defer cache.Close()
+	for _, name := range slices.Sorted(maps.Keys(executors)) {
+		injected, err := injectExecutor(ctx, inst.Name, name, reg, cache)
+		if err != nil {
+			return result, launchError(ctx, ErrCodeStart, err)
+		}
+		result.Injected[name] = injected
+		if opts.ReloadSignal == "" {
+			continue
+		}
+		if err := docker.CommandContext(ctx, docker.Query, "kill", "--signal", opts.ReloadSignal, executors[name]).Run(); err != nil {
+			logging.Warnf("Sending %s to executor %s: %v", opts.ReloadSignal, name, err)
+			continue
+		}
+		logging.Infof("Sent %s to executor %s", opts.ReloadSignal, logging.BoldMagenta(name))
+		result.Signaled = append(result.Signaled, name)
+	}
+	return result, nil
+}
+
+// returns the ID of a service's running (or paused) container, or "" when it has none, as for an executor merged by a mission that has not run it, or its container has exited
+var containerIDFor = func(ctx context.Context, instanceName, svc string) (string, error) {
+	containerID, err := health.GetContainerIDForService(ctx, instanceName, svc)
+	if err != nil || containerID == "" {
+		return "", err
+	}
+	out, err := docker.CommandContext(ctx, docker.Query, "inspect", "--format", "{{.State.Running}}", containerID).Output()
+	if err != nil {
+		return "", fmt.Errorf("inspecting container of %s: %w", svc, err)
+	}
+	if strings.TrimSpace(string(out)) != "true" {
+		return "", nil
+	}
+	return containerID, nil
+}
+
+// maps each of executors that has a running container to that container's ID; the others have nothing to re-inject into and are skipped
+func runningExecutors(ctx context.Context, instanceName string, executors []string) (map[string]string, error) {
+	running := make(map[string]string, len(executors))
+	for _, name := range executors {
+		containerID, err := containerIDFor(ctx, instanceName, name)
+		if err != nil {
+			return nil, fmt.Errorf("locating container for executor service %s: %w", name, err)
+		}
+		if containerID == "" {
+			logging.Debugf("Not re-injecting executor %s: it has no running container", name)
+			continue
+		}
+		running[name] = containerID
+	}
+	return running, nil
+}
+
+// extracts the payloads of cf's services against the instance and merges the services into its compose file without starting them, returning them grouped by profile; services already part of the instance are reported as a conflict
+func (i *Instance) mergeServices(ctx context.Context, cf *compose.ComposeFile, hostLibPath string, reg *registry.Registry) (map[string][]string, error) {
+	current, err := compose.LoadRawYAML(i.ComposeFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading merged compose of %s: %w", i.Name, err)
+	}
+	currentServices, _ := current["services"].(map[string]interface{})
+	for name := range cf.Services {
+		if _, exists := currentServices[name]; exists {
+			return nil, codedError(ErrCodeConflict, fmt.Errorf("service %s is already part of %s", name, i.Name))
+		}
+	}
+
+	added, profilesMap, _, err := buildMergedCompose(ctx, cf, i.LibPath, hostLibPath, nil, i.Name, reg)
+	if err != nil {
+		return nil, launchError(ctx, ErrCodeExtraction, err)
+	}
+	if currentServices == nil {
+		currentServices = map[string]interface{}{}
+		current["services"] = currentServices
+	}
+	for name, svc := range added["services"].(map[string]interface{}) {
+		compose.SetLabels(svc.(map[string]interface{}), i.Labels)
+		currentServices[name] = svc
+	}
+	if err := writeComposeToDisk(i.ComposeFile, current); err != nil {
+		return nil, err
+	}
+
+	for profile, services := range profilesMap {
+		i.profilesMap[profile] = append(i.profilesMap[profile], services...)
+	}
+	i.profiles = orderedProfiles(extractProfileNames(i.profilesMap))
+	i.reg = reg
+	return profilesMap, nil
+}
@@ -0,0 +1,61 @@
+package coral
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"testing"
+)
+
+func TestRunningExecutors(t *testing.T) {
+	tests := []struct {
+		name       string
+		containers map[string]string // service → running container ID; missing services have none
+		lookupErr  error
+		executors  []string
+		want       map[string]string
+		wantErr    bool
+	}{
+		{
+			name:       "launched executors are re-injected",
+			containers: map[string]string{"nav": "c1", "arm": "c2"},
+			executors:  []string{"arm", "nav"},
+			want:       map[string]string{"nav": "c1", "arm": "c2"},
+		},
+		{
+			// mission run -f merges its executors into the compose file; once they have exited, or before they run, they have no running container
+			name:       "after a mission run",
+			containers: map[string]string{"nav": "c1"},
+			executors:  []string{"nav", "pick", "place"},
+			want:       map[string]string{"nav": "c1"},
+		},
+		{
+			name:      "no running executors",
+			executors: []string{"pick"},
+			want:      map[string]string{},
+		},
+		{
+			name:      "lookup failure",
+			lookupErr: errors.New("docker unavailable"),
+			executors: []string{"nav"},
+			wantErr:   true,
+		},
+	}
+
+	lookup := containerIDFor
+	t.Cleanup(func() { containerIDFor = lookup })
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			containerIDFor = func(_ context.Context, _, svc string) (string, error) {
+				return tt.containers[svc], tt.lookupErr
+			}
+			got, err := runningExecutors(context.Background(), "inst", tt.executors)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !maps.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
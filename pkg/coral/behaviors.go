@@ -0,0 +1,167 @@
+package coral
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"coral_cli/internal/docker"
+	"coral_cli/internal/libs"
+	"coral_cli/internal/logging"
+)
+
+// options for Launcher.Behaviors
+type BehaviorsOptions struct {
+	ComposePath      string // compose file whose services provide the payloads; every local image with a coral.profile label when empty
+	EnvFile          string // optional .env file for compose substitutions; ./.env is used when empty and present
+	SkipVersionCheck bool   // skip the coral.version compatibility check between the launcher and the compose file's images
+}
+
+// a node, behavior library or interface library one payload provides
+type Behavior struct {
+	libs.CatalogEntry
+	Image        string `json:"image"`             // the payload's image
+	Service      string `json:"service,omitempty"` // the compose service running the image
+	BtcppVersion string `json:"btcpp_version,omitempty"`
+	RosDistro    string `json:"ros_distro,omitempty"`
+}
+
+// the payload an entry's PayloadID, ShadowedBy and Clashes refer to
+type behaviorProvider struct {
+	image   string
+	service string
+	labels  map[string]string
+	source  libs.LibrarySource
+}
+
+// the service providing the payload, or else its image
+func (p *behaviorProvider) name() string {
+	if p.service != "" {
+		return p.service
+	}
+	return p.image
+}
+
+// lists every node (from the payloads' node manifests), behavior and interface the payloads of a compose file, or of every local Coral image, provide. Each payload is extracted into a temporary directory; nothing is launched. Payloads for the same ROS distro are compared as if all were injected into one executor, so libraries with the same name are marked as shadowed by the one the default priority policy would inject, and nodes several payloads register name the others as clashes. PayloadID, ShadowedBy and Clashes hold service names, or image references when listing local images
+func (l *Launcher) Behaviors(ctx context.Context, opts BehaviorsOptions) ([]Behavior, error) {
+	tmpLib, err := os.MkdirTemp("", "coral-behaviors-*")
+	if err != nil {
+		return nil, fmt.Errorf("creating temp lib dir: %w", err)
+	}
+	defer os.RemoveAll(tmpLib)
+
+	providers, err := l.behaviorProviders(ctx, opts, tmpLib)
+	if err != nil {
+		return nil, err
+	}
+
+	// payloads for different ROS distros never share an executor
+	byDistro := make(map[string]map[string]libs.LibrarySource)
+	for id, p := range providers {
+		distro := p.labels["coral.ros_distro"]
+		if byDistro[distro] == nil {
+			byDistro[distro] = make(map[string]libs.LibrarySource)
+		}
+		byDistro[distro][id] = p.source
+	}
+	behaviors := []Behavior{}
+	for _, distro := range slices.Sorted(maps.Keys(byDistro)) {
+		entries, err := libs.Catalog(byDistro[distro], ConflictPriority)
+		if err != nil {
+			return nil, codedError(ErrCodeExtraction, err)
+		}
+		for _, e := range entries {
+			p := providers[e.PayloadID]
+			e.PayloadID = p.name()
+			if e.ShadowedBy != "" {
+				e.ShadowedBy = providers[e.ShadowedBy].name()
+			}
+			for i, id := range e.Clashes {
+				e.Clashes[i] = providers[id].name()
+			}
+			behaviors = append(behaviors, Behavior{
+				CatalogEntry: e,
+				Image:        p.image,
+				Service:      p.service,
+				BtcppVersion: p.labels["coral.btcpp_version"],
+				RosDistro:    distro,
+			})
+		}
+	}
+	kinds := []string{libs.KindNode, libs.KindBehavior, libs.KindInterface}
+	slices.SortStableFunc(behaviors, func(a, b Behavior) int {
+		return cmp.Or(
+			cmp.Compare(slices.Index(kinds, a.Kind), slices.Index(kinds, b.Kind)),
+			cmp.Compare(a.Name, b.Name),
+			cmp.Compare(a.PayloadID, b.PayloadID))
+	})
+	return behaviors, nil
+}
+
+// extracts the payloads of opts' compose file, or of every local Coral image, into lib, returning them keyed by staging ID
+func (l *Launcher) behaviorProviders(ctx context.Context, opts BehaviorsOptions, lib string) (map[string]*behaviorProvider, error) {
+	providers := make(map[string]*behaviorProvider)
+	// every service is its own payload, as at launch, while an image listed under several tags is extracted once
+	add := func(image, service string) (*behaviorProvider, error) {
+		name := service
+		if name == "" {
+			name = "catalog"
+		}
+		stagingDir, id, err := libs.ExtractLibraries(ctx, image, name, lib)
+		if err != nil {
+			return nil, err
+		}
+		if p, ok := providers[id]; ok {
+			return p, nil
+		}
+		labels, err := libs.GetImageLabels(ctx, image)
+		if err != nil {
+			return nil, err
+		}
+		p := &behaviorProvider{image: image, service: service, labels: labels, source: libs.LibrarySource{Dir: stagingDir}}
+		providers[id] = p
+		return p, nil
+	}
+
+	if opts.ComposePath == "" {
+		out, err := docker.CommandContext(ctx, docker.Query, "images", "--filter", "label=coral.profile", "--format", "{{.Repository}}:{{.Tag}}").Output()
+		if err != nil {
+			return nil, launchError(ctx, ErrCodeImage, fmt.Errorf("listing images: %w", err))
+		}
+		for _, image := range strings.Fields(string(out)) {
+			if strings.Contains(image, "<none>") {
+				continue
+			}
+			if _, err := add(image, ""); err != nil {
+				if ctx.Err() != nil {
+					return nil, codedError(ErrCodeInterrupted, ctx.Err())
+				}
+				logging.Warnf("Skipping %s: %v", image, err)
+			}
+		}
+		return providers, nil
+	}
+
+	cf, _, err := loadCompose(LaunchOptions{ComposePath: opts.ComposePath, EnvFile: opts.EnvFile})
+	if err != nil {
+		return nil, err
+	}
+	if err := checkImagesLocal(ctx, cf, l.Version, opts.SkipVersionCheck); err != nil {
+		return nil, launchError(ctx, ErrCodeImage, fmt.Errorf("checking images: %w", err))
+	}
+	for _, name := range slices.Sorted(maps.Keys(cf.Services)) {
+		image := cf.Services[name]["image"].(string) // already validated in checkImagesLocal
+		p, err := add(image, name)
+		if err != nil {
+			return nil, launchError(ctx, ErrCodeExtraction, fmt.Errorf("extracting %s for service %s: %w", image, name, err))
+		}
+		if p.source.Priority, err = libraryPriority(p.labels, cf.Services[name]); err != nil {
+			return nil, codedError(ErrCodeInvalidInput, fmt.Errorf("service %s: %w", name, err))
+		}
+	}
+	return providers, nil
+}
@@ -0,0 +1,273 @@
+package coral
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"coral_cli/internal/compose"
+	"coral_cli/internal/libs"
+	"coral_cli/internal/logging"
+	"coral_cli/internal/registry"
+)
+
+var validProfiles = map[string]bool{"drivers": true, "skillsets": true, "executors": true}
+
+// verifies every service image is local (pulling if needed), carries a valid coral.profile and, unless skipped, a coral.version whose major matches version
+func checkImagesLocal(ctx context.Context, cf *compose.ComposeFile, version string, skipVersionCheck bool) error {
+	selfMajor, err := parseMajorVersion(version)
+	checkVersion := !skipVersionCheck && err == nil // skip for dev builds or when flag is set
+
+	for name, svc := range cf.Services {
+		raw, ok := svc["image"]
+		if !ok || raw == nil {
+			return fmt.Errorf("missing 'image' in service %s", name)
+		}
+		image, ok := raw.(string)
+		if !ok {
+			return fmt.Errorf("expected string for 'image' in service %s", name)
+		}
+		if _, err := libs.GetImageID(ctx, image); err != nil {
+			return fmt.Errorf("checking image %s for service %s: %w", image, name, err)
+		}
+		labels, err := libs.GetImageLabels(ctx, image)
+		if err != nil {
+			return fmt.Errorf("reading labels for service %s: %w", name, err)
+		}
+		profile := labels["coral.profile"]
+		if profile == "" {
+			return fmt.Errorf("image %s (service %s) is missing required label coral.profile", image, name)
+		}
+		if !validProfiles[profile] {
+			return fmt.Errorf("image %s (service %s) has invalid coral.profile %q: must be one of drivers, skillsets, executors", image, name, profile)
+		}
+		if checkVersion {
+			coralVer := labels["coral.version"]
+			if coralVer == "" {
+				return fmt.Errorf("image %s (service %s) is missing required label coral.version", image, name)
+			}
+			imageMajor, err := parseMajorVersion(coralVer)
+			if err != nil {
+				return fmt.Errorf("image %s (service %s) has unparseable coral.version %q: %w", image, name, coralVer, err)
+			}
+			if imageMajor != selfMajor {
+				return fmt.Errorf("image %s (service %s) coral.version %q is incompatible with CLI version %s (major %d != %d)",
+					image, name, coralVer, version, imageMajor, selfMajor)
+			}
+		}
+	}
+	return nil
+}
+
+// extracts library artifacts from each service image, records them in the registry, and builds the merged compose map; the registry key of each service's extraction is returned alongside its services by profile
+func buildMergedCompose(ctx context.Context, cf *compose.ComposeFile, lib, hostLib string,
+	profilesToStart []string, instanceName string, reg *registry.Registry,
+) (compose.RawCompose, map[string][]string, map[string]string, error) {
+
+	rawCompose, err := cf.ToMap()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("converting compose to map: %w", err)
+	}
+	rawServices := rawCompose["services"].(map[string]interface{})
+	merged := compose.RawCompose{"services": map[string]interface{}{}}
+	profilesMap := map[string][]string{}
+	extractions := map[string]string{}
+
+	for name, svc := range cf.Services {
+		image := svc["image"].(string)
+		labels, err := libs.GetImageLabels(ctx, image)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("reading labels for service %s: %w", name, err)
+		}
+		profile := labels["coral.profile"] // already validated non-empty and valid in checkImagesLocal
+
+		if len(profilesToStart) > 0 {
+			matched := false
+			for _, p := range profilesToStart {
+				if p == profile {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				continue
+			}
+		}
+		profilesMap[profile] = append(profilesMap[profile], name)
+
+		stagingDir, imageID, err := libs.ExtractLibraries(ctx, image, name, lib)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("extracting %s for service %s: %w", image, name, err)
+		}
+
+		if v := labels["coral.btcpp_version"]; v != "" {
+			if _, err := parseVersionRange(v); err != nil {
+				logging.Warnf("Service %s: coral.btcpp_version is not a version range, so only executors with exactly %q will receive its libraries: %v", name, v, err)
+			}
+		}
+		priority, err := libraryPriority(labels, svc)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("service %s: %w", name, err)
+		}
+		extractions[name] = imageID
+		rec := registry.ExtractionRecord{
+			ImageID:      imageID,
+			StagingDir:   stagingDir,
+			PayloadID:    imageID,
+			BtcppVersion: labels["coral.btcpp_version"],
+			RosDistro:    labels["coral.ros_distro"],
+			Priority:     priority,
+			Services:     []string{name},
+			Images:       []string{image},
+		}
+		if err := reg.RecordExtraction(rec, instanceName); err != nil {
+			logging.Warnf("recording extraction for %s: %v", name, err)
+		}
+
+		logging.Infof("Extracted interfaces from %s for %s", image, logging.BoldMagenta(name))
+
+		// merge docker.yaml from the staging directory into the service config
+		baseSvc := rawServices[name].(map[string]interface{})
+		extractedPath := filepath.Join(stagingDir, "docker.yaml")
+		var mergedSvc map[string]interface{}
+		if _, err := os.Stat(extractedPath); err == nil {
+			extracted, err := compose.LoadRawYAML(extractedPath)
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("loading extracted compose for %s: %w", name, err)
+			}
+			mergedSvc = compose.MergeServiceConfigs(baseSvc, extracted)
+		} else {
+			mergedSvc = baseSvc
+		}
+
+		// resolve and inject device mappings from devices.yaml
+		devicesPath := filepath.Join(stagingDir, "devices.yaml")
+		if _, err := os.Stat(devicesPath); err == nil {
+			df, err := compose.LoadDevicesFile(devicesPath)
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("loading devices.yaml for %s: %w", name, err)
+			}
+			devPaths, err := compose.ResolveDevicePaths(df, name)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			if len(devPaths) > 0 {
+				existing, _ := mergedSvc["devices"].([]interface{})
+				for _, p := range devPaths {
+					existing = append(existing, p)
+				}
+				mergedSvc["devices"] = existing
+				logging.Infof("Mapped %d device(s) for %s", len(devPaths), logging.BoldMagenta(name))
+			}
+		}
+
+		// rewrite relative host volume paths to absolute - and when CORAL is running inside Docker, rebase them onto hostLib so the Docker daemon can reach them
+		if volumes, ok := mergedSvc["volumes"].([]interface{}); ok {
+			for i, v := range volumes {
+				volStr, ok := v.(string)
+				if !ok {
+					continue
+				}
+				parts := strings.SplitN(volStr, ":", 2)
+				if len(parts) != 2 {
+					continue
+				}
+				hostPath := parts[0]
+				if filepath.IsAbs(hostPath) || strings.HasPrefix(hostPath, "${") {
+					// if the path is absolute and starts with libPath, rebase onto hostLib
+					if hostLib != "" && strings.HasPrefix(hostPath, lib) {
+						hostPath = hostLib + hostPath[len(lib):]
+						volumes[i] = fmt.Sprintf("%s:%s", hostPath, parts[1])
+					}
+					continue
+				}
+				absPath, err := filepath.Abs(hostPath)
+				if err == nil {
+					volumes[i] = fmt.Sprintf("%s:%s", absPath, parts[1])
+				}
+			}
+		}
+
+		mergedSvc["profiles"] = []interface{}{profile}
+		merged["services"].(map[string]interface{})[name] = mergedSvc
+	}
+
+	return merged, profilesMap, extractions, nil
+}
+
+// returns the priority a service's libraries carry in conflicts: x-coral.priority in the service config, or else the image's coral.priority label, or else zero
+func libraryPriority(labels map[string]string, svc map[string]interface{}) (int, error) {
+	if xc, ok := svc["x-coral"].(map[string]interface{}); ok {
+		if raw, ok := xc["priority"]; ok {
+			priority, ok := raw.(int)
+			if !ok {
+				return 0, fmt.Errorf("x-coral priority %v is not an integer", raw)
+			}
+			return priority, nil
+		}
+	}
+	if raw := labels["coral.priority"]; raw != "" {
+		priority, err := strconv.Atoi(raw)
+		if err != nil {
+			return 0, fmt.Errorf("coral.priority label %q is not an integer", raw)
+		}
+		return priority, nil
+	}
+	return 0, nil
+}
+
+// reads a merged compose file written by Launch and returns its services grouped by Coral profile
+func profilesFromCompose(composePath string) (map[string][]string, error) {
+	env := map[string]string{}
+	cf, err := compose.ParseCompose(composePath, env)
+	if err != nil {
+		return nil, fmt.Errorf("parsing compose file for profile extraction: %w", err)
+	}
+
+	profilesMap := make(map[string][]string)
+	for name, svc := range cf.Services {
+		if rawProfiles, ok := svc["profiles"]; ok {
+			switch p := rawProfiles.(type) {
+			case []interface{}:
+				for _, val := range p {
+					if str, ok := val.(string); ok {
+						profilesMap[str] = append(profilesMap[str], name)
+					}
+				}
+			}
+		}
+	}
+	return profilesMap, nil
+}
+
+func extractProfileNames(profiles map[string][]string) []string {
+	var names []string
+	for k := range profiles {
+		names = append(names, k)
+	}
+	return names
+}
+
+func writeComposeToDisk(path string, data compose.RawCompose) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating output dir: %w", err)
+	}
+	return compose.SaveRawYAML(path, data)
+}
+
+func orderedProfiles(input []string) []string {
+	seen := make(map[string]bool)
+	var result []string
+	for _, key := range []string{"drivers", "skillsets", "executors"} {
+		for _, profile := range input {
+			if profile == key && !seen[profile] {
+				result = append(result, profile)
+				seen[profile] = true
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,52 @@
+package coral
+
+import (
+	"context"
+	"errors"
+)
+
+// classifies a failure so callers (and the CLI's structured output) can react without parsing messages
+type ErrorCode string
+
+const (
+	ErrCodeInvalidInput ErrorCode = "invalid_input"       // bad options, compose file or env file
+	ErrCodeNotFound     ErrorCode = "not_found"           // no instance matched
+	ErrCodeConflict     ErrorCode = "conflict"            // the requested handle is already used by another instance
+	ErrCodeImage        ErrorCode = "image_check_failed"  // an image is missing, unpullable or mislabelled
+	ErrCodeExtraction   ErrorCode = "extraction_failed"   // payload libraries could not be extracted or merged
+	ErrCodeStart        ErrorCode = "start_failed"        // a profile or executor failed to start
+	ErrCodeShutdown     ErrorCode = "shutdown_failed"     // containers or files could not be cleaned up
+	ErrCodeInterrupted  ErrorCode = "interrupted"         // the operation was cancelled
+	ErrCodeVerification ErrorCode = "verification_failed" // an image is not compliant with Coral's standards
+	ErrCodeMission      ErrorCode = "mission_failed"      // an executor of a mission exited non-zero, timed out or could not be run
+	ErrCodeInvalidTree  ErrorCode = "invalid_tree"        // a behavior tree uses nodes or ports the injected behaviors do not provide
+	ErrCodeUnknown      ErrorCode = "error"
+)
+
+// wraps an error with an ErrorCode
+type Error struct {
+	Code ErrorCode
+	Err  error
+}
+
+func (e *Error) Error() string { return e.Err.Error() }
+func (e *Error) Unwrap() error { return e.Err }
+
+func codedError(code ErrorCode, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Code: code, Err: err}
+}
+
+// returns the code of the outermost coded error in err's chain; cancellations map to ErrCodeInterrupted and anything else to ErrCodeUnknown
+func CodeOf(err error) ErrorCode {
+	var ce *Error
+	if errors.As(err, &ce) {
+		return ce.Code
+	}
+	if errors.Is(err, context.Canceled) {
+		return ErrCodeInterrupted
+	}
+	return ErrCodeUnknown
+}
@@ -0,0 +1,262 @@
+package coral
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"coral_cli/internal/docker"
+	"coral_cli/internal/logging"
+	"coral_cli/internal/util"
+)
+
+// how an emergency stop halts containers
+type EStopMode string
+
+const (
+	EStopPause EStopMode = "pause" // freeze containers (docker pause) so they can be resumed where they left off
+	EStopKill  EStopMode = "kill"  // kill containers outright; resuming restarts them
+)
+
+// the profiles an emergency stop halts: executors so no further commands are issued, drivers so the hardware stops moving
+var estopProfiles = []string{"drivers", "executors"}
+
+// configures EStop and Resume
+type EStopOptions struct {
+	Mode     EStopMode // EStopPause when empty; ignored by Resume
+	Selector Selector  // the instances to stop or resume; every instance when empty
+}
+
+// what an emergency stop or resume did; each one is appended to ~/.coral_cli/estop.log
+type EStopEvent struct {
+	Time      string        `json:"time"`
+	Action    string        `json:"action"`           // pause, kill or resume
+	Reason    string        `json:"reason,omitempty"` // set for events triggered by the interlock
+	Instances []EStopTarget `json:"instances"`
+}
+
+type EStopTarget struct {
+	Instance   string              `json:"instance"`
+	Containers map[string][]string `json:"containers"` // container IDs by profile
+	Errors     []string            `json:"errors,omitempty"`
+}
+
+// pauses or kills every executor and driver container of the selected instances, all in parallel and without going through compose, so the robot stops as quickly as possible. Instance metadata is kept (with the stopped containers recorded) so the instances can be resumed or shut down afterwards
+func EStop(ctx context.Context, opts EStopOptions) (*EStopEvent, error) {
+	mode := opts.Mode
+	if mode == "" {
+		mode = EStopPause
+	}
+	if mode != EStopPause && mode != EStopKill {
+		return nil, codedError(ErrCodeInvalidInput, fmt.Errorf("invalid estop mode %q: must be pause or kill", mode))
+	}
+	metas, err := selectMetadata(ctx, opts.Selector)
+	if err != nil {
+		return nil, err
+	}
+	if len(metas) == 0 && !opts.Selector.Empty() {
+		return nil, codedError(ErrCodeNotFound, fmt.Errorf("no instances found matching criteria"))
+	}
+	containers, err := coralContainers(ctx)
+	if err != nil {
+		return nil, codedError(ErrCodeUnknown, fmt.Errorf("listing containers: %w", err))
+	}
+
+	event := &EStopEvent{Time: time.Now().Format(time.RFC3339Nano), Action: string(mode)}
+	targets := make([]EStopTarget, len(metas))
+	var wg sync.WaitGroup
+	for i, meta := range metas {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			targets[i] = haltContainers(ctx, meta.Name, mode, instanceContainers(containers, meta.Name, estopProfiles))
+		}()
+	}
+	wg.Wait()
+	event.Instances = targets
+
+	var errs []error
+	for i, meta := range metas {
+		t := targets[i]
+		for _, e := range t.Errors {
+			errs = append(errs, fmt.Errorf("%s: %s", t.Instance, e))
+		}
+		if len(t.Containers) == 0 {
+			continue
+		}
+		meta.EStop = &util.EStopRecord{Mode: string(mode), At: event.Time, Containers: t.Containers}
+		if err := writeInstanceMetadata(meta); err != nil {
+			errs = append(errs, fmt.Errorf("recording estop for %s: %w", meta.Name, err))
+		}
+	}
+	if err := appendEStopLog(event); err != nil {
+		logging.Warnf("Recording estop event: %v", err)
+	}
+	return event, codedError(ErrCodeShutdown, errors.Join(errs...))
+}
+
+// pauses or kills the given containers of an instance in parallel; with EStopPause, containers that are already paused are only recorded
+func haltContainers(ctx context.Context, instanceName string, mode EStopMode, containers []coralContainer) EStopTarget {
+	t := EStopTarget{Instance: instanceName, Containers: map[string][]string{}}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, c := range containers {
+		if mode == EStopPause && c.state == "paused" {
+			t.Containers[c.profile] = append(t.Containers[c.profile], c.id)
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := docker.CommandContext(ctx, docker.Compose, string(mode), c.id).Run()
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				t.Errors = append(t.Errors, fmt.Sprintf("%s %s: %v", mode, c.service, err))
+				return
+			}
+			t.Containers[c.profile] = append(t.Containers[c.profile], c.id)
+		}()
+	}
+	wg.Wait()
+	return t
+}
+
+// undoes an emergency stop of the selected instances: paused containers are unpaused and killed ones started again, drivers before executors
+func Resume(ctx context.Context, opts EStopOptions) (*EStopEvent, error) {
+	metas, err := selectMetadata(ctx, opts.Selector)
+	if err != nil {
+		return nil, err
+	}
+	if len(metas) == 0 && !opts.Selector.Empty() {
+		return nil, codedError(ErrCodeNotFound, fmt.Errorf("no instances found matching criteria"))
+	}
+	event := &EStopEvent{Time: time.Now().Format(time.RFC3339Nano), Action: "resume", Instances: []EStopTarget{}}
+	var errs []error
+	for _, meta := range metas {
+		if meta.EStop == nil {
+			continue
+		}
+		t := resumeContainers(ctx, meta.Name, EStopMode(meta.EStop.Mode), meta.EStop.Containers)
+		event.Instances = append(event.Instances, t)
+		for _, e := range t.Errors {
+			errs = append(errs, fmt.Errorf("%s: %s", t.Instance, e))
+		}
+		if len(t.Errors) > 0 {
+			continue
+		}
+		meta.EStop = nil
+		if err := writeInstanceMetadata(meta); err != nil {
+			errs = append(errs, fmt.Errorf("clearing estop for %s: %w", meta.Name, err))
+		}
+	}
+	if len(event.Instances) > 0 {
+		if err := appendEStopLog(event); err != nil {
+			logging.Warnf("Recording resume event: %v", err)
+		}
+	}
+	return event, codedError(ErrCodeStart, errors.Join(errs...))
+}
+
+// unpauses or restarts the given containers profile by profile in start order, in parallel within each profile
+func resumeContainers(ctx context.Context, instanceName string, mode EStopMode, containers map[string][]string) EStopTarget {
+	action := "unpause"
+	if mode == EStopKill {
+		action = "start"
+	}
+	t := EStopTarget{Instance: instanceName, Containers: map[string][]string{}}
+	var mu sync.Mutex
+	for _, profile := range orderedProfiles(extractProfileNames(containers)) {
+		var wg sync.WaitGroup
+		for _, id := range containers[profile] {
+			wg.Add(1)
+			go func(id string) {
+				defer wg.Done()
+				err := docker.CommandContext(ctx, docker.Compose, action, id).Run()
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					t.Errors = append(t.Errors, fmt.Sprintf("%s %s: %v", action, shortID(id), err))
+					return
+				}
+				t.Containers[profile] = append(t.Containers[profile], id)
+			}(id)
+		}
+		wg.Wait()
+	}
+	return t
+}
+
+type coralContainer struct {
+	id      string
+	project string
+	service string
+	profile string
+	state   string
+}
+
+// filters containers down to those of one instance in the given profiles
+func instanceContainers(containers []coralContainer, instanceName string, profiles []string) []coralContainer {
+	var selected []coralContainer
+	for _, c := range containers {
+		if c.project == instanceName && slices.Contains(profiles, c.profile) {
+			selected = append(selected, c)
+		}
+	}
+	return selected
+}
+
+// lists every running (or paused) container carrying a coral.profile label in a single docker call
+func coralContainers(ctx context.Context) ([]coralContainer, error) {
+	out, err := docker.CommandContext(ctx, docker.Query, "ps",
+		"--filter", "label=coral.profile",
+		"--format", "{{.ID}}\t{{.Label \"com.docker.compose.project\"}}\t{{.Label \"com.docker.compose.service\"}}\t{{.Label \"coral.profile\"}}\t{{.State}}").Output()
+	if err != nil {
+		return nil, err
+	}
+	var containers []coralContainer
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 5 {
+			continue
+		}
+		containers = append(containers, coralContainer{id: fields[0], project: fields[1], service: fields[2], profile: fields[3], state: fields[4]})
+	}
+	return containers, nil
+}
+
+func appendEStopLog(event *EStopEvent) error {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(home, ".coral_cli")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, "estop.log"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
@@ -0,0 +1,289 @@
+package coral
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"coral_cli/internal/docker"
+	"coral_cli/internal/health"
+	"coral_cli/internal/libs"
+	"coral_cli/internal/logging"
+	"coral_cli/internal/registry"
+	"coral_cli/internal/util"
+)
+
+// performs the three-phase executor launch:
+//  1. docker compose create  — allocate containers without starting them
+//  2. InjectLibraries        — stream behavior/interface .so files and exported subtrees into each container, sourced from the staging dirs within the instance's injection scope
+//  3. docker compose start   — start the containers
+//
+// the scope is stored with the instance: by default only payloads extracted for the instance itself, with group adding those of other instances in its group and global every payload in the library directory; each executor's import filter and version labels narrow the selection further.
+//
+// executors given identical library sets share an archive from cache. The libraries injected into each executor are returned keyed by service name
+func createAndStartExecutors(ctx context.Context, instanceName, composePath string, executorServices []string,
+	reg *registry.Registry, cache *libs.ArchiveCache) (map[string][]registry.InjectedLib, error) {
+
+	createArgs := append([]string{"compose", "-p", instanceName, "-f", composePath, "--profile", "executors", "create"}, executorServices...)
+	createCmd := docker.CommandContext(ctx, docker.Compose, createArgs...)
+	createCmd.Stdout = logging.CommandOutput()
+	createCmd.Stderr = logging.CommandErrors()
+	if err := createCmd.Run(); err != nil {
+		return nil, fmt.Errorf("creating executor containers: %w", err)
+	}
+
+	injections := make(map[string][]registry.InjectedLib, len(executorServices))
+	for _, svc := range executorServices {
+		injected, err := injectExecutor(ctx, instanceName, svc, reg, cache)
+		if err != nil {
+			return nil, err
+		}
+		injections[svc] = injected
+	}
+
+	startArgs := append([]string{"compose", "-p", instanceName, "-f", composePath, "start"}, executorServices...)
+	startCmd := docker.CommandContext(ctx, docker.Compose, startArgs...)
+	startCmd.Stdout = logging.CommandOutput()
+	startCmd.Stderr = logging.CommandErrors()
+	return injections, startCmd.Run()
+}
+
+// which payloads' libraries are injected into an instance's executors
+type InjectionScope string
+
+const (
+	ScopeInstance InjectionScope = "instance" // only payloads extracted for the instance itself (the default)
+	ScopeGroup    InjectionScope = "group"    // payloads extracted for any instance in the same group
+	ScopeGlobal   InjectionScope = "global"   // every payload in the lib dir, whichever instance extracted it
+)
+
+func validInjectionScope(scope InjectionScope) bool {
+	switch scope {
+	case "", ScopeInstance, ScopeGroup, ScopeGlobal:
+		return true
+	}
+	return false
+}
+
+// how two payloads providing different libraries with the same name are resolved; files with identical content never conflict
+type ConflictPolicy = libs.ConflictPolicy
+
+const (
+	ConflictPriority = libs.ConflictPriority // the payload with the highest priority wins, then the newer file (the default)
+	ConflictNewest   = libs.ConflictNewest   // the newer file wins
+	ConflictError    = libs.ConflictError    // injection fails
+)
+
+// the injection settings recorded for an instance
+type injectionPolicy struct {
+	scope     InjectionScope
+	members   map[string]bool // instances whose payloads fall within scope; nil when every payload does
+	conflicts ConflictPolicy
+	compose   string // the instance's merged compose file
+}
+
+// copies every compatible payload library within the instance's injection scope into an executor service's (created or running) container, resolving conflicts by the instance's policy, and records the injection
+func injectExecutor(ctx context.Context, instanceName, svc string, reg *registry.Registry, cache *libs.ArchiveCache) ([]registry.InjectedLib, error) {
+	containerID, sources, policy, err := executorLibraries(ctx, instanceName, svc, reg)
+	if err != nil {
+		return nil, err
+	}
+	injected, err := libs.InjectLibraries(ctx, containerID, sources, policy.conflicts, cache)
+	if err != nil {
+		if errors.Is(err, libs.ErrLibraryConflict) {
+			return nil, codedError(ErrCodeConflict, fmt.Errorf("injecting libraries into %s: %w", svc, err))
+		}
+		return nil, fmt.Errorf("injecting libraries into %s: %w", svc, err)
+	}
+	if err := reg.RecordInjection(containerID, instanceName, string(policy.scope), injected); err != nil {
+		logging.Warnf("recording injection for %s: %v", svc, err)
+	}
+	active, rejected, broken := 0, 0, 0
+	for _, l := range injected {
+		switch {
+		case l.Rejected != "":
+			rejected++
+		case !l.Shadowed:
+			active++
+			if len(l.Problems) > 0 {
+				broken++
+			}
+		}
+	}
+	logging.Infof("Injected %d libraries into executor %s", active, logging.BoldMagenta(svc))
+	if rejected > 0 {
+		logging.Warnf("Rejected %d libraries built for another platform than executor %s", rejected, svc)
+	}
+	if broken > 0 {
+		logging.Warnf("%d libraries injected into executor %s may fail to load", broken, svc)
+	}
+	checkExecutorTree(ctx, containerID, svc, sources, injected)
+	return injected, nil
+}
+
+// locates an executor service's container and returns it along with the payloads (by registry key) within the instance's injection scope whose libraries are compatible with it, and the instance's injection policy
+func executorLibraries(ctx context.Context, instanceName, svc string, reg *registry.Registry) (string, map[string]libs.LibrarySource, injectionPolicy, error) {
+	containerID, err := health.GetContainerIDForService(ctx, instanceName, svc)
+	if err != nil {
+		return "", nil, injectionPolicy{}, fmt.Errorf("locating container for executor service %s: %w", svc, err)
+	}
+	if containerID == "" {
+		return "", nil, injectionPolicy{}, fmt.Errorf("no container for executor service %s", svc)
+	}
+
+	execLabels, err := libs.GetContainerLabels(ctx, containerID)
+	if err != nil {
+		return "", nil, injectionPolicy{}, fmt.Errorf("reading labels for executor %s: %w", svc, err)
+	}
+	policy, err := loadInjectionPolicy(instanceName)
+	if err != nil {
+		return "", nil, injectionPolicy{}, err
+	}
+	filter, err := executorImportFilter(policy.compose, svc, execLabels)
+	if err != nil {
+		return "", nil, injectionPolicy{}, fmt.Errorf("reading import filter of executor %s: %w", svc, err)
+	}
+
+	sources, err := acceptedPayloads(ctx, svc, execLabels, filter, reg.AllExtractions(), policy)
+	if err != nil {
+		return "", nil, injectionPolicy{}, err
+	}
+	return containerID, sources, policy, nil
+}
+
+// returns the payloads among extractions (by registry key) that an executor service with the given labels takes libraries from: those within the policy's scope that pass its import filter and are compatible with its BT.CPP and ROS versions
+func acceptedPayloads(ctx context.Context, svc string, execLabels map[string]string, filter importFilter,
+	extractions map[string]registry.ExtractionRecord, policy injectionPolicy) (map[string]libs.LibrarySource, error) {
+
+	execBtcpp := execLabels["coral.btcpp_version"]
+	execRos := execLabels["coral.ros_distro"]
+	sources := make(map[string]libs.LibrarySource)
+	for imageID, rec := range extractions {
+		if policy.members != nil && !slices.ContainsFunc(rec.InstanceIDs, func(id string) bool { return policy.members[id] }) {
+			logging.Debugf("Not injecting libraries from %s into executor %s: outside %s scope", rec.PayloadID, svc, policy.scope)
+			continue
+		}
+		// an executor always accepts its own payload
+		if !slices.Contains(rec.Services, svc) {
+			accepted, reason, err := filter.accepts(ctx, rec)
+			if err != nil {
+				return nil, fmt.Errorf("filtering payloads for executor %s: %w", svc, err)
+			}
+			if !accepted {
+				logging.Infof("Not injecting libraries from %s into executor %s: %s", payloadName(rec), svc, reason)
+				continue
+			}
+		}
+		var reasons []string
+		if reason := btcppIncompatibility(rec.BtcppVersion, execBtcpp); reason != "" {
+			reasons = append(reasons, reason)
+		}
+		if rec.RosDistro != execRos {
+			reasons = append(reasons, fmt.Sprintf("ROS distro mismatch %q != %q", rec.RosDistro, execRos))
+		}
+		if len(reasons) > 0 {
+			logging.Warnf("Cowardly refusing to inject libraries from %s into executor %s: %s",
+				rec.PayloadID, svc, strings.Join(reasons, ", "))
+			continue
+		}
+		sources[imageID] = libs.LibrarySource{Dir: rec.StagingDir, Priority: rec.Priority}
+	}
+	return sources, nil
+}
+
+// returns the injection scope and conflict policy recorded for an instance, along with the instances whose payloads fall within the scope
+func loadInjectionPolicy(instanceName string) (injectionPolicy, error) {
+	meta, _, err := util.LoadInstanceMetadata(instanceName)
+	if err != nil {
+		return injectionPolicy{}, fmt.Errorf("loading metadata of %s: %w", instanceName, err)
+	}
+	policy := injectionPolicy{
+		scope:     InjectionScope(meta.InjectionScope),
+		members:   map[string]bool{instanceName: true},
+		conflicts: ConflictPolicy(meta.ConflictPolicy),
+		compose:   meta.ComposeFile,
+	}
+	if policy.scope == "" {
+		policy.scope = ScopeInstance
+	}
+	if policy.conflicts == "" {
+		policy.conflicts = ConflictPriority
+	}
+	switch policy.scope {
+	case ScopeGlobal:
+		policy.members = nil
+	case ScopeGroup:
+		if meta.Group == "" {
+			break
+		}
+		metadataList, err := util.LoadAllMetadata()
+		if err != nil {
+			return injectionPolicy{}, fmt.Errorf("loading metadata: %w", err)
+		}
+		for _, m := range metadataList {
+			if m.Group == meta.Group {
+				policy.members[m.Name] = true
+			}
+		}
+	}
+	return policy, nil
+}
+
+// brings up each profile in order, gating executors on drivers and skillsets becoming healthy; a cancelled context aborts the health gate and executor delay immediately. The libraries injected into each executor are returned keyed by service name
+func startProfiles(ctx context.Context, profiles []string, instanceName, composePath string,
+	executorDelay, healthTimeout time.Duration, profilesMap map[string][]string,
+	reg *registry.Registry) (map[string][]registry.InjectedLib, error) {
+
+	var injected map[string][]registry.InjectedLib
+
+	for _, profile := range profiles {
+		if profile == "executors" {
+			// gate on drivers + skillsets being healthy before touching executors (only if they implement health checks)
+			var depServices []string
+			for _, p := range []string{"drivers", "skillsets"} {
+				depServices = append(depServices, profilesMap[p]...)
+			}
+			if len(depServices) > 0 {
+				logging.Infof("Waiting for drivers and skillsets to become healthy...")
+				if err := health.WaitForHealthy(ctx, instanceName, depServices, healthTimeout); err != nil {
+					if ctx.Err() != nil {
+						return nil, ctx.Err()
+					}
+					logging.Warnf("Health gate timed out: %v — proceeding anyway", err)
+				}
+			}
+			if executorDelay > 0 {
+				logging.Infof("Waiting %.0fs before starting executors...", executorDelay.Seconds())
+				select {
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				case <-time.After(executorDelay):
+				}
+			}
+			cache := libs.NewArchiveCache()
+			defer cache.Close()
+			var err error
+			injected, err = createAndStartExecutors(ctx, instanceName, composePath, profilesMap["executors"], reg, cache)
+			if err != nil {
+				return nil, fmt.Errorf("starting executors: %w", err)
+			}
+			continue
+		}
+
+		logging.Infof("Starting %s (%d): %s",
+			logging.BoldMagenta(profile), len(profilesMap[profile]),
+			logging.BoldMagenta(fmt.Sprintf("%v", profilesMap[profile])))
+
+		cmd := docker.CommandContext(ctx, docker.Compose, "compose", "-p", instanceName, "-f", composePath,
+			"--profile", profile, "up", "-d")
+		cmd.Stdout = logging.CommandOutput()
+		cmd.Stderr = logging.CommandErrors()
+		if err := cmd.Run(); err != nil {
+			return nil, fmt.Errorf("starting profile %s: %w", profile, err)
+		}
+	}
+	return injected, nil
+}
@@ -0,0 +1,172 @@
+package coral
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"coral_cli/internal/compose"
+	"coral_cli/internal/libs"
+	"coral_cli/internal/registry"
+)
+
+// which payloads an executor accepts libraries from. Each pattern is either key=value, matching payload images carrying that label, or a glob matched against the services that ran the payload and its image reference (with and without registry path and tag)
+type importFilter struct {
+	include []string // when set, only payloads matching one of these are accepted
+	exclude []string // payloads matching any of these are rejected
+}
+
+// reads an executor's import filter from the import section of its service's x-coral in the merged compose file, or else from its coral.import.include and coral.import.exclude labels (comma-separated)
+func executorImportFilter(composeFile, svc string, labels map[string]string) (importFilter, error) {
+	filter := importFilter{
+		include: splitPatterns(labels["coral.import.include"]),
+		exclude: splitPatterns(labels["coral.import.exclude"]),
+	}
+	merged, err := compose.LoadRawYAML(composeFile)
+	if err != nil {
+		return filter, fmt.Errorf("reading merged compose: %w", err)
+	}
+	services, _ := merged["services"].(map[string]interface{})
+	service, _ := services[svc].(map[string]interface{})
+	xc, _ := service["x-coral"].(map[string]interface{})
+	imports, ok := xc["import"].(map[string]interface{})
+	if !ok {
+		return filter, filter.validate()
+	}
+	for key, dest := range map[string]*[]string{"include": &filter.include, "exclude": &filter.exclude} {
+		raw, ok := imports[key]
+		if !ok {
+			continue
+		}
+		patterns, err := patternList(raw)
+		if err != nil {
+			return filter, fmt.Errorf("x-coral import %s of %s: %w", key, svc, err)
+		}
+		*dest = patterns
+	}
+	return filter, filter.validate()
+}
+
+// rejects malformed glob patterns
+func (f importFilter) validate() error {
+	for _, pattern := range slices.Concat(f.include, f.exclude) {
+		if strings.Contains(pattern, "=") {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return codedError(ErrCodeInvalidInput, fmt.Errorf("invalid import pattern %q: %w", pattern, err))
+		}
+	}
+	return nil
+}
+
+func (f importFilter) empty() bool {
+	return len(f.include) == 0 && len(f.exclude) == 0
+}
+
+// reports whether the executor accepts a payload's libraries, and if not, why
+func (f importFilter) accepts(ctx context.Context, rec registry.ExtractionRecord) (bool, string, error) {
+	if f.empty() {
+		return true, "", nil
+	}
+	var imageLabels map[string]string // looked up only for label patterns
+	matches := func(pattern string) (bool, error) {
+		if key, value, ok := strings.Cut(pattern, "="); ok {
+			if imageLabels == nil {
+				labels, err := libs.GetImageLabels(ctx, rec.ImageID)
+				if err != nil {
+					return false, fmt.Errorf("reading labels of %s: %w", rec.PayloadID, err)
+				}
+				imageLabels = labels
+				if imageLabels == nil {
+					imageLabels = map[string]string{}
+				}
+			}
+			v, ok := imageLabels[key]
+			return ok && v == value, nil
+		}
+		for _, name := range payloadNames(rec) {
+			if ok, _ := path.Match(pattern, name); ok {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	for _, pattern := range f.exclude {
+		ok, err := matches(pattern)
+		if err != nil {
+			return false, "", err
+		}
+		if ok {
+			return false, fmt.Sprintf("excluded by %q", pattern), nil
+		}
+	}
+	if len(f.include) == 0 {
+		return true, "", nil
+	}
+	for _, pattern := range f.include {
+		ok, err := matches(pattern)
+		if err != nil {
+			return false, "", err
+		}
+		if ok {
+			return true, "", nil
+		}
+	}
+	return false, "not included", nil
+}
+
+// the names a payload can be matched by: the services that ran it, and each image reference in full, without its tag, and without its registry path
+func payloadNames(rec registry.ExtractionRecord) []string {
+	names := slices.Clone(rec.Services)
+	for _, image := range rec.Images {
+		names = append(names, image)
+		repo := image
+		if i := strings.LastIndex(repo, ":"); i > strings.LastIndex(repo, "/") {
+			repo = repo[:i]
+		}
+		names = append(names, repo, path.Base(repo))
+	}
+	return names
+}
+
+// splits a comma-separated list of patterns, dropping empty entries
+func splitPatterns(s string) []string {
+	var patterns []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// reads an x-coral pattern list, given either as a YAML list or as a comma-separated string
+func patternList(raw interface{}) ([]string, error) {
+	switch v := raw.(type) {
+	case string:
+		return splitPatterns(v), nil
+	case []interface{}:
+		var patterns []string
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected a string, got %v", item)
+			}
+			patterns = append(patterns, strings.TrimSpace(s))
+		}
+		return patterns, nil
+	}
+	return nil, fmt.Errorf("expected a list or comma-separated string, got %v", raw)
+}
+
+// a payload's first service name, for messages; its payload ID when no service is recorded
+func payloadName(rec registry.ExtractionRecord) string {
+	if len(rec.Services) > 0 {
+		return rec.Services[0]
+	}
+	return rec.PayloadID
+}
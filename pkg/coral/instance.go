@@ -0,0 +1,338 @@
+package coral
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"coral_cli/internal/cleanup"
+	"coral_cli/internal/docker"
+	"coral_cli/internal/health"
+	"coral_cli/internal/logging"
+	"coral_cli/internal/registry"
+	"coral_cli/internal/util"
+)
+
+// persisted description of an instance as stored under ~/.coral_cli/instances
+type Metadata = util.InstanceMetadata
+
+// a library file copied into an executor container
+type InjectedLib = registry.InjectedLib
+
+// health events emitted by Instance.Events
+type (
+	Event     = health.HealthEvent
+	EventType = health.EventType
+)
+
+const (
+	EventContainerUnhealthy = health.EventContainerUnhealthy
+	EventContainerExited    = health.EventContainerExited
+	EventLibraryDegraded    = health.EventLibraryDegraded
+	EventContainerRecovered = health.EventContainerRecovered
+)
+
+// handle to a launched (or previously launched) Coral instance
+type Instance struct {
+	Metadata
+
+	// libraries injected into each executor service by the launch that produced this handle; nil for handles returned by Open and List
+	Injected map[string][]InjectedLib
+
+	profiles    []string
+	profilesMap map[string][]string
+	reg         *registry.Registry
+	// true when this process launched the instance and is therefore responsible for removing its files even if it was not detached
+	owned bool
+}
+
+// upper bounds on the docker commands Coral runs, by kind; see SetTimeouts
+type Timeouts = docker.Timeouts
+
+// the timeouts in effect until SetTimeouts is called
+var DefaultTimeouts = docker.DefaultTimeouts
+
+// bounds every docker command started afterwards (lookups, library copies, pulls and compose operations); a zero duration leaves that kind of command unbounded
+func SetTimeouts(t Timeouts) {
+	docker.SetTimeouts(t)
+}
+
+// configures Instance.Shutdown; a graceful stop goes through profiles in reverse start order (executors, skillsets, drivers)
+type ShutdownOptions struct {
+	Kill            bool                     // forcefully kill containers before removing them instead of stopping them gracefully
+	Timeout         time.Duration            // grace period for each profile before its remaining containers are killed; compose's default when zero
+	ProfileTimeouts map[string]time.Duration // per-profile grace periods overriding Timeout
+	Signal          string                   // signal that asks containers to stop (e.g. SIGINT); each service's stop_signal, SIGTERM by default, when empty
+	Escalate        <-chan struct{}          // closing it cuts a graceful stop short and kills whatever is still running
+	Progress        func(StopProgress)       // called as services stop and with a countdown during a graceful stop; may be nil
+}
+
+// progress of a graceful stop, reported through ShutdownOptions.Progress
+type StopProgress = cleanup.StopProgress
+
+// current state of a single service container
+type ServiceStatus struct {
+	Service     string `json:"service"`
+	Profile     string `json:"profile"`
+	ContainerID string `json:"container_id,omitempty"`
+	Status      string `json:"status"`
+	ExitCode    int    `json:"exit_code"`
+}
+
+// returns a handle to an existing instance by name; the handle does not own the instance, so shutting down an attached (non-detached) instance leaves file removal to the process that launched it
+func Open(name string) (*Instance, error) {
+	meta, _, err := util.LoadInstanceMetadata(name)
+	if err != nil {
+		return nil, codedError(ErrCodeNotFound, fmt.Errorf("no instance found with name %s: %w", name, err))
+	}
+	return openMetadata(*meta)
+}
+
+// returns handles to every known instance
+func List() ([]*Instance, error) {
+	metadataList, err := util.LoadAllMetadata()
+	if err != nil {
+		return nil, fmt.Errorf("loading metadata: %w", err)
+	}
+	var instances []*Instance
+	for _, meta := range metadataList {
+		inst, err := openMetadata(meta)
+		if err != nil {
+			logging.Warnf("Skipping %s: %v", meta.Name, err)
+			continue
+		}
+		instances = append(instances, inst)
+	}
+	return instances, nil
+}
+
+func openMetadata(meta util.InstanceMetadata) (*Instance, error) {
+	profilesMap, err := profilesFromCompose(meta.ComposeFile)
+	if err != nil {
+		return nil, err
+	}
+	return &Instance{
+		Metadata:    meta,
+		profiles:    orderedProfiles(extractProfileNames(profilesMap)),
+		profilesMap: profilesMap,
+	}, nil
+}
+
+// returns the instance's Coral profiles in start order
+func (i *Instance) Profiles() []string {
+	return append([]string(nil), i.profiles...)
+}
+
+// returns the services of a profile as listed in the merged compose file
+func (i *Instance) Services(profile string) []string {
+	return append([]string(nil), i.profilesMap[profile]...)
+}
+
+// stops the instance's containers and, when this handle owns the instance or it was launched detached, removes its compose file, metadata, staging directories and registry records. Cancelling ctx abandons the shutdown at once: docker commands in flight are interrupted, the metadata is kept and marked abandoned, and Prune finishes the job later
+func (i *Instance) Shutdown(ctx context.Context, opts ShutdownOptions) error {
+	done := make(chan error, 1)
+	go func() { done <- i.shutdown(ctx, opts) }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		if err := i.markAbandoned(); err != nil {
+			logging.Warnf("Marking %s as abandoned: %v", i.Name, err)
+		}
+		return codedError(ErrCodeInterrupted, fmt.Errorf("shutdown of %s abandoned: %w", i.Name, ctx.Err()))
+	}
+}
+
+func (i *Instance) shutdown(ctx context.Context, opts ShutdownOptions) error {
+	// another process (e.g. a launch replacing this instance's handle) may already have shut it down and removed it
+	if _, _, err := util.LoadInstanceMetadata(i.Name); errors.Is(err, fs.ErrNotExist) {
+		logging.Infof("%s has already been shut down elsewhere", logging.BoldMagenta(i.Name))
+		return nil
+	}
+	var errs []error
+	stopOpts := cleanup.StopOptions{
+		Kill:            opts.Kill,
+		Timeout:         opts.Timeout,
+		ProfileTimeouts: opts.ProfileTimeouts,
+		Signal:          opts.Signal,
+		Services:        i.profilesMap,
+		Escalate:        opts.Escalate,
+		Progress:        opts.Progress,
+	}
+	if err := cleanup.StopCompose(ctx, i.Name, i.ComposeFile, i.profiles, stopOpts); err != nil {
+		errs = append(errs, codedError(ErrCodeShutdown, fmt.Errorf("stopping compose: %w", err)))
+	}
+	// an abandoned shutdown keeps the files Prune needs
+	if ctx.Err() != nil {
+		return errors.Join(errs...)
+	}
+	if i.owned || i.Detached {
+		if err := cleanup.RemoveInstanceFiles(ctx, i.Name); err != nil {
+			errs = append(errs, codedError(ErrCodeShutdown, fmt.Errorf("removing files: %w", err)))
+		}
+	} else {
+		logging.Warnf("Instance %s is not detached; file removal will be left to the foreground process.", i.Name)
+	}
+	return errors.Join(errs...)
+}
+
+func (i *Instance) markAbandoned() error {
+	i.Abandoned = true
+	return writeInstanceMetadata(i.Metadata)
+}
+
+// kills and removes every instance whose shutdown was abandoned, along with its files and registry records, returning the metadata of each instance pruned
+func Prune(ctx context.Context) ([]Metadata, error) {
+	metadataList, err := util.LoadAllMetadata()
+	if err != nil {
+		return nil, fmt.Errorf("loading metadata: %w", err)
+	}
+	var pruned []Metadata
+	var errs []error
+	for _, meta := range metadataList {
+		if !meta.Abandoned {
+			continue
+		}
+		logging.Infof("Pruning %s...", logging.BoldMagenta(meta.Name))
+		var stopErr error
+		if profilesMap, err := profilesFromCompose(meta.ComposeFile); err == nil {
+			profiles := orderedProfiles(extractProfileNames(profilesMap))
+			stopErr = cleanup.StopCompose(ctx, meta.Name, meta.ComposeFile, profiles, cleanup.StopOptions{Kill: true})
+		} else {
+			// the compose file went missing with the abandoned cleanup, so fall back to the compose project label
+			stopErr = cleanup.RemoveProjectContainers(ctx, meta.Name)
+		}
+		if stopErr != nil {
+			errs = append(errs, codedError(ErrCodeShutdown, fmt.Errorf("stopping %s: %w", meta.Name, stopErr)))
+			continue
+		}
+		if err := cleanup.RemoveInstanceFiles(ctx, meta.Name); err != nil {
+			errs = append(errs, codedError(ErrCodeShutdown, fmt.Errorf("removing files of %s: %w", meta.Name, err)))
+		}
+		pruned = append(pruned, meta)
+	}
+	return pruned, errors.Join(errs...)
+}
+
+// reports the state of every service container in the instance, ordered by profile then service name; services without a container are reported as "missing"
+func (i *Instance) Status(ctx context.Context) ([]ServiceStatus, error) {
+	var statuses []ServiceStatus
+	for _, profile := range i.profiles {
+		services := i.Services(profile)
+		sort.Strings(services)
+		for _, svc := range services {
+			st := ServiceStatus{Service: svc, Profile: profile, Status: "missing"}
+			id, err := health.GetContainerIDForService(ctx, i.Name, svc)
+			if err != nil {
+				return nil, fmt.Errorf("locating container for %s: %w", svc, err)
+			}
+			if id != "" {
+				st.ContainerID = id
+				st.Status, st.ExitCode = health.InspectStatus(ctx, id)
+			}
+			statuses = append(statuses, st)
+		}
+	}
+	return statuses, nil
+}
+
+// blocks until every executor container of the instance has exited and returns their exit codes by service; executors without a container are left out, and an instance without executors returns at once
+func (i *Instance) WaitExecutors(ctx context.Context) (map[string]int, error) {
+	type exit struct {
+		svc  string
+		code int
+		err  error
+	}
+	// every container is located before any wait starts, so a failed lookup leaves no waiter behind
+	containers := make(map[string]string) // service → container ID
+	for _, svc := range i.Services("executors") {
+		id, err := health.GetContainerIDForService(ctx, i.Name, svc)
+		if err != nil {
+			return nil, fmt.Errorf("locating container for %s: %w", svc, err)
+		}
+		if id != "" {
+			containers[svc] = id
+		}
+	}
+	exits := make(chan exit, len(containers))
+	for svc, id := range containers {
+		go func() {
+			code, err := waitContainer(ctx, id)
+			exits <- exit{svc, code, err}
+		}()
+	}
+	codes := make(map[string]int, len(containers))
+	var errs []error
+	for range len(containers) {
+		e := <-exits
+		if e.err != nil {
+			errs = append(errs, fmt.Errorf("waiting for %s: %w", e.svc, e.err))
+			continue
+		}
+		codes[e.svc] = e.code
+	}
+	return codes, errors.Join(errs...)
+}
+
+// blocks until a container exits and returns its exit code
+func waitContainer(ctx context.Context, containerID string) (int, error) {
+	out, err := docker.CommandContext(ctx, docker.Wait, "wait", containerID).Output()
+	if err != nil {
+		return -1, err
+	}
+	code, err := strconv.Atoi(strings.TrimSpace(string(out)))
+	if err != nil {
+		return -1, fmt.Errorf("reading exit code %q: %w", strings.TrimSpace(string(out)), err)
+	}
+	return code, nil
+}
+
+// streams the logs of every container in the instance until ctx is cancelled or all containers exit; when all is false only new output is shown
+func (i *Instance) Logs(ctx context.Context, all bool) (<-chan struct{}, <-chan error, error) {
+	return TailLogs(ctx, []*Instance{i}, all)
+}
+
+// streams the logs of every container across several instances with one colour per service, until ctx is cancelled or all containers exit
+func TailLogs(ctx context.Context, instances []*Instance, all bool) (<-chan struct{}, <-chan error, error) {
+	var containers []util.ContainerInfo
+	for _, inst := range instances {
+		instanceContainers, err := docker.GetContainerInfo(ctx, inst.Name, inst.ComposeFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("getting container info for %s: %w", inst.Name, err)
+		}
+		containers = append(containers, instanceContainers...)
+	}
+	if len(containers) == 0 {
+		return nil, nil, fmt.Errorf("no containers found matching criteria")
+	}
+	done, errCh := docker.TailLogs(containers, ctx.Done(), all)
+	return done, errCh, nil
+}
+
+// starts a health monitor for the instance and returns its events; the channel is closed once ctx is cancelled
+func (i *Instance) Events(ctx context.Context) (<-chan Event, error) {
+	if i.reg == nil {
+		reg, err := registry.Load(i.LibPath)
+		if err != nil {
+			return nil, fmt.Errorf("loading registry: %w", err)
+		}
+		i.reg = reg
+	}
+	return health.NewMonitor(i.Name, i.Labels, i.reg).Start(ctx), nil
+}
+
+func writeInstanceMetadata(meta util.InstanceMetadata) error {
+	home, _ := os.UserHomeDir()
+	storeDir := filepath.Join(home, ".coral_cli", "instances")
+	os.MkdirAll(storeDir, 0755)
+	data, _ := json.MarshalIndent(meta, "", "  ")
+	return os.WriteFile(filepath.Join(storeDir, meta.Name+".json"), data, 0644)
+}
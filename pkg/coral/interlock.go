@@ -0,0 +1,115 @@
+package coral
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"coral_cli/internal/logging"
+	"coral_cli/internal/util"
+)
+
+// configures Instance.Interlock
+type InterlockOptions struct {
+	Mode         EStopMode // how executors are halted; EStopPause when empty
+	ManualResume bool      // leave executors halted after critical services recover until coral resume is run
+}
+
+// watches health events for drivers and skillsets whose images are labelled coral.critical=true: as soon as one becomes unhealthy or exits, every executor of the instance is paused (or killed) and the halt recorded like an emergency stop; once all failed critical services are healthy again the executors are resumed, unless opts.ManualResume is set. Every event is passed on through the returned channel, which is closed when events is
+func (i *Instance) Interlock(ctx context.Context, events <-chan Event, opts InterlockOptions) <-chan Event {
+	mode := opts.Mode
+	if mode == "" {
+		mode = EStopPause
+	}
+	out := make(chan Event, cap(events))
+	go func() {
+		defer close(out)
+		failed := make(map[string]bool) // critical services currently down, by container ID
+		for ev := range events {
+			if ev.Critical && (ev.Profile == "drivers" || ev.Profile == "skillsets") {
+				switch ev.Type {
+				case EventContainerUnhealthy, EventContainerExited:
+					failed[ev.ContainerID] = true
+					if len(failed) == 1 {
+						i.tripInterlock(ctx, mode, fmt.Sprintf("critical service %s: %s", ev.ServiceName, ev.Detail))
+					}
+				case EventContainerRecovered:
+					if failed[ev.ContainerID] {
+						delete(failed, ev.ContainerID)
+						if len(failed) == 0 {
+							i.releaseInterlock(ctx, ev.ServiceName, opts.ManualResume)
+						}
+					}
+				}
+			}
+			out <- ev
+		}
+	}()
+	return out
+}
+
+// halts every executor of the instance and records the halt in its metadata so coral resume can undo it
+func (i *Instance) tripInterlock(ctx context.Context, mode EStopMode, reason string) {
+	logging.Failuref("Interlock tripped for %s — %s; halting executors", logging.BoldMagenta(i.Name), reason)
+	containers, err := coralContainers(ctx)
+	if err != nil {
+		logging.Failuref("Interlock could not list executors of %s: %v", i.Name, err)
+		return
+	}
+	t := haltContainers(ctx, i.Name, mode, instanceContainers(containers, i.Name, []string{"executors"}))
+	for _, e := range t.Errors {
+		logging.Failuref("Interlock: %s", e)
+	}
+	event := &EStopEvent{Time: time.Now().Format(time.RFC3339Nano), Action: string(mode), Reason: reason, Instances: []EStopTarget{t}}
+	if err := appendEStopLog(event); err != nil {
+		logging.Warnf("Recording interlock event: %v", err)
+	}
+	if len(t.Containers) == 0 {
+		return
+	}
+	logging.Warnf("Halted %d executor container(s) of %s", len(t.Containers["executors"]), logging.BoldMagenta(i.Name))
+	meta, _, err := util.LoadInstanceMetadata(i.Name)
+	if err != nil {
+		logging.Warnf("Recording interlock for %s: %v", i.Name, err)
+		return
+	}
+	// a manual emergency stop already in place takes precedence and is not overwritten
+	if meta.EStop != nil {
+		return
+	}
+	meta.EStop = &util.EStopRecord{Mode: string(mode), At: event.Time, Containers: t.Containers, Interlock: true, Reason: reason}
+	if err := writeInstanceMetadata(*meta); err != nil {
+		logging.Warnf("Recording interlock for %s: %v", i.Name, err)
+	}
+}
+
+// resumes the executors halted by tripInterlock once every failed critical service has recovered, unless that is left to coral resume
+func (i *Instance) releaseInterlock(ctx context.Context, service string, manual bool) {
+	meta, _, err := util.LoadInstanceMetadata(i.Name)
+	if err != nil {
+		logging.Warnf("Releasing interlock for %s: %v", i.Name, err)
+		return
+	}
+	if meta.EStop == nil || !meta.EStop.Interlock {
+		return
+	}
+	if manual {
+		logging.Warnf("Critical service %s of %s has recovered; run `coral resume -n %s` to resume executors", service, logging.BoldMagenta(i.Name), i.Name)
+		return
+	}
+	t := resumeContainers(ctx, i.Name, EStopMode(meta.EStop.Mode), meta.EStop.Containers)
+	if err := appendEStopLog(&EStopEvent{Time: time.Now().Format(time.RFC3339Nano), Action: "resume", Reason: fmt.Sprintf("critical service %s recovered", service), Instances: []EStopTarget{t}}); err != nil {
+		logging.Warnf("Recording interlock event: %v", err)
+	}
+	if len(t.Errors) > 0 {
+		for _, e := range t.Errors {
+			logging.Failuref("Interlock: %s", e)
+		}
+		return
+	}
+	meta.EStop = nil
+	if err := writeInstanceMetadata(*meta); err != nil {
+		logging.Warnf("Clearing interlock for %s: %v", i.Name, err)
+	}
+	logging.Successf("Critical services of %s have recovered; executors resumed", logging.BoldMagenta(i.Name))
+}
@@ -0,0 +1,307 @@
+// Package coral exposes Coral's instance lifecycle (launch, inspect, tail, shut down) as a Go API so tooling can embed Coral without exec'ing the CLI; the coral command is a thin wrapper over this package.
+package coral
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"coral_cli/internal/cleanup"
+	"coral_cli/internal/compose"
+	"coral_cli/internal/libs"
+	"coral_cli/internal/logging"
+	"coral_cli/internal/registry"
+	"coral_cli/internal/util"
+)
+
+// configures a single Launch; the zero value launches every profile of ./compose.yaml (or its usual aliases) in foreground mode
+type LaunchOptions struct {
+	ComposePath      string            // compose file to launch; resolved like docker compose when empty
+	EnvFile          string            // optional .env file for compose substitutions; ./.env is used when empty and present
+	Handle           string            // optional handle for the instance; must not be used by another instance unless Replace is set
+	Replace          bool              // gracefully shut down the instance already using Handle, releasing its libraries, before launching
+	ReplaceOptions   ShutdownOptions   // how the replaced instance is shut down
+	Group            string            // optional group for the instance
+	Labels           map[string]string // user labels recorded with the instance and set on each of its containers; keys may not use the coral. or com.docker. prefixes
+	Detached         bool              // whether the instance outlives the launching process
+	ExecutorDelay    time.Duration     // additional delay after health checks pass before starting executors
+	HealthTimeout    time.Duration     // how long to wait for drivers/skillsets to become healthy before starting executors
+	Profiles         []string          // profiles to launch (drivers, skillsets, executors); all when empty
+	LibDir           string            // overrides CORAL_LIB
+	SkipVersionCheck bool              // skip the coral.version compatibility check between the launcher and images
+	InjectionScope   InjectionScope    // which payloads' libraries are injected into the instance's executors; ScopeInstance when empty
+	ConflictPolicy   ConflictPolicy    // how libraries with the same name from different payloads are resolved; ConflictPriority when empty
+}
+
+// launches Coral instances; Version is the launcher's own version, used to check the coral.version label of each image (dev or unparseable versions skip the check)
+type Launcher struct {
+	Version string
+}
+
+func NewLauncher(version string) *Launcher {
+	return &Launcher{Version: version}
+}
+
+// extracts payload libraries, writes the merged compose and instance metadata, and brings every requested profile up in order; on failure or context cancellation everything created so far is rolled back, otherwise the returned Instance owns the running containers
+func (l *Launcher) Launch(ctx context.Context, opts LaunchOptions) (*Instance, error) {
+	for key := range opts.Labels {
+		if key == "" || strings.HasPrefix(key, "coral.") || strings.HasPrefix(key, "com.docker.") {
+			return nil, codedError(ErrCodeInvalidInput, fmt.Errorf("invalid label %q: keys must be non-empty and may not start with coral. or com.docker.", key))
+		}
+	}
+
+	if !validInjectionScope(opts.InjectionScope) {
+		return nil, codedError(ErrCodeInvalidInput, fmt.Errorf("invalid injection scope %q: must be instance, group or global", opts.InjectionScope))
+	}
+	if opts.InjectionScope == "" {
+		opts.InjectionScope = ScopeInstance
+	}
+	if opts.ConflictPolicy == "" {
+		opts.ConflictPolicy = ConflictPriority
+	}
+	if !opts.ConflictPolicy.Valid() {
+		return nil, codedError(ErrCodeInvalidInput, fmt.Errorf("invalid conflict policy %q: must be error, priority or newest", opts.ConflictPolicy))
+	}
+
+	replaced, err := handleOwner(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	parsedCompose, env, err := loadCompose(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// resolve lib path
+	var libPath string
+	if opts.LibDir != "" {
+		libPath = opts.LibDir
+	} else {
+		libPath = env["CORAL_LIB"]
+	}
+	if strings.TrimSpace(libPath) == "" {
+		libPath, err = filepath.Abs("./lib")
+		if err != nil {
+			return nil, fmt.Errorf("resolving ./lib: %w", err)
+		}
+		if _, err := os.Stat(libPath); os.IsNotExist(err) {
+			if err := os.Mkdir(libPath, 0755); err != nil {
+				return nil, fmt.Errorf("creating lib dir: %w", err)
+			}
+		}
+	} else {
+		if _, err := os.Stat(libPath); os.IsNotExist(err) {
+			return nil, codedError(ErrCodeInvalidInput, fmt.Errorf("lib dir %q does not exist", libPath))
+		}
+	}
+
+	hostLibPath, err := hostLib(env)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkImagesLocal(ctx, parsedCompose, l.Version, opts.SkipVersionCheck); err != nil {
+		return nil, launchError(ctx, ErrCodeImage, fmt.Errorf("checking images: %w", err))
+	}
+
+	// the instance being replaced goes only once the new one has passed every check that does not touch the running system
+	if replaced != nil {
+		logging.Infof("Replacing %s with handle %s...", logging.BoldMagenta(replaced.Name), logging.BoldMagenta(opts.Handle))
+		if err := replaced.Shutdown(ctx, opts.ReplaceOptions); err != nil {
+			return nil, launchError(ctx, ErrCodeShutdown, fmt.Errorf("shutting down %s: %w", replaced.Name, err))
+		}
+	}
+
+	uid := uuid.New()
+	instanceName := fmt.Sprintf("coral-%x", uid[:4])
+	logging.Infof("Launching new instance %s", logging.BoldMagentaHi(instanceName))
+
+	// load (or create) the persistent registry
+	reg, err := registry.Load(libPath)
+	if err != nil {
+		return nil, fmt.Errorf("loading registry: %w", err)
+	}
+
+	// declared here so the deferred abort below can reference it even if launch fails before writeComposeToDisk is reached
+	outputPath := filepath.Join(libPath, "compose", instanceName+".yaml")
+
+	// guard: if launch fails before metadata is written, abort removes any staging dirs and registry records written so far
+	launched := false
+	defer func() {
+		if !launched {
+			cleanup.AbortInstance(instanceName, libPath, outputPath, reg)
+		}
+	}()
+
+	mergedCompose, profilesMap, _, err := buildMergedCompose(
+		ctx, parsedCompose, libPath, hostLibPath, opts.Profiles, instanceName, reg)
+	if err != nil {
+		if ctx.Err() != nil {
+			logging.Warnf("Interrupt during extraction — rolling back %s...", logging.BoldMagenta(instanceName))
+		}
+		return nil, launchError(ctx, ErrCodeExtraction, err)
+	}
+
+	profiles := extractProfileNames(profilesMap)
+	servicesRaw, ok := mergedCompose["services"]
+	if !ok {
+		return nil, codedError(ErrCodeInvalidInput, fmt.Errorf("merged compose file has no 'services' section"))
+	}
+	services, ok := servicesRaw.(map[string]interface{})
+	if !ok || len(services) == 0 {
+		return nil, codedError(ErrCodeInvalidInput, fmt.Errorf("merged compose file has no valid services"))
+	}
+	for _, svc := range services {
+		compose.SetLabels(svc.(map[string]interface{}), opts.Labels)
+	}
+
+	if err := writeComposeToDisk(outputPath, mergedCompose); err != nil {
+		return nil, err
+	}
+	meta := util.InstanceMetadata{
+		Name:           instanceName,
+		ComposeFile:    outputPath,
+		CreatedAt:      time.Now().Format(time.RFC3339),
+		LibPath:        libPath,
+		Handle:         opts.Handle,
+		Group:          opts.Group,
+		Detached:       opts.Detached,
+		Labels:         opts.Labels,
+		InjectionScope: string(opts.InjectionScope),
+		ConflictPolicy: string(opts.ConflictPolicy),
+	}
+	if err := writeInstanceMetadata(meta); err != nil {
+		return nil, err
+	}
+
+	profiles = orderedProfiles(profiles)
+	if len(profiles) == 0 {
+		return nil, codedError(ErrCodeInvalidInput, fmt.Errorf("no valid profiles to run"))
+	}
+
+	// past this point metadata is written; suppress the deferred abort and use RemoveInstanceFiles (which reads metadata) for any remaining cleanup
+	launched = true
+
+	if err := ctx.Err(); err != nil {
+		logging.Warnf("Interrupt during init — cleaning up %s...", logging.BoldMagenta(instanceName))
+		cleanup.RemoveInstanceFiles(context.Background(), instanceName)
+		logging.Successf("Done")
+		return nil, codedError(ErrCodeInterrupted, err)
+	}
+
+	inst := &Instance{
+		Metadata:    meta,
+		profiles:    profiles,
+		profilesMap: profilesMap,
+		reg:         reg,
+		owned:       true,
+	}
+
+	injected, err := startProfiles(ctx, profiles, instanceName, outputPath, opts.ExecutorDelay, opts.HealthTimeout, profilesMap, reg)
+	if err != nil {
+		code := ErrCodeStart
+		if errors.Is(err, libs.ErrLibraryConflict) {
+			code = ErrCodeConflict
+		}
+		if ctx.Err() != nil {
+			code = ErrCodeInterrupted
+			logging.Warnf("Interrupt received — shutting down %s...", logging.BoldMagenta(instanceName))
+		} else {
+			logging.Warnf("Launch failed — cleaning up %s...", logging.BoldMagenta(instanceName))
+		}
+		// ctx may already be cancelled, and the rollback must run regardless
+		_ = inst.Shutdown(context.Background(), ShutdownOptions{Kill: true})
+		return nil, codedError(code, err)
+	}
+	inst.Injected = injected
+	return inst, nil
+}
+
+// loads the environment (the env file, then the process environment) and parses the compose file named by opts with it
+func loadCompose(opts LaunchOptions) (*compose.ComposeFile, map[string]string, error) {
+	// load environment
+	env := make(map[string]string)
+	resolvedEnvFile, err := util.ResolveEnvFile(opts.EnvFile)
+	if err != nil {
+		return nil, nil, codedError(ErrCodeInvalidInput, fmt.Errorf("resolving env file: %w", err))
+	}
+	if resolvedEnvFile != "" {
+		env, err = compose.LoadEnvFile(resolvedEnvFile)
+		if err != nil {
+			return nil, nil, codedError(ErrCodeInvalidInput, fmt.Errorf("loading .env: %w", err))
+		}
+	}
+	for _, e := range os.Environ() {
+		parts := strings.SplitN(e, "=", 2)
+		if len(parts) == 2 {
+			if _, exists := env[parts[0]]; !exists {
+				env[parts[0]] = parts[1]
+			}
+		}
+	}
+
+	// resolve compose file
+	resolvedComposePath, err := util.ResolveComposeFile(opts.ComposePath)
+	if err != nil {
+		return nil, nil, codedError(ErrCodeInvalidInput, err)
+	}
+	parsedCompose, err := compose.ParseCompose(resolvedComposePath, env)
+	if err != nil {
+		return nil, nil, codedError(ErrCodeInvalidInput, fmt.Errorf("parsing compose file: %w", err))
+	}
+	return parsedCompose, env, nil
+}
+
+// returns the host path of the lib dir when Coral itself runs inside Docker (CORAL_IS_DOCKER=true), and "" otherwise; volume mounts in compose files need host paths, while docker cp operations stream through the socket and need no special handling
+func hostLib(env map[string]string) (string, error) {
+	if env["CORAL_IS_DOCKER"] != "true" {
+		return "", nil
+	}
+	hostLibPath, ok := env["CORAL_HOST_LIB"]
+	if !ok || strings.TrimSpace(hostLibPath) == "" {
+		return "", codedError(ErrCodeInvalidInput, fmt.Errorf("CORAL_HOST_LIB is required when CORAL_IS_DOCKER=true"))
+	}
+	return hostLibPath, nil
+}
+
+// enforces handle uniqueness: returns the instance already using opts.Handle when opts.Replace is set, so Launch can shut it down, and refuses the launch otherwise
+func handleOwner(opts LaunchOptions) (*Instance, error) {
+	if opts.Handle == "" {
+		return nil, nil
+	}
+	metadataList, err := util.LoadAllMetadata()
+	if err != nil {
+		return nil, fmt.Errorf("loading metadata: %w", err)
+	}
+	for _, meta := range metadataList {
+		if meta.Handle != opts.Handle {
+			continue
+		}
+		if !opts.Replace {
+			return nil, codedError(ErrCodeConflict, fmt.Errorf("handle %s is already used by instance %s; use --replace to redeploy it", opts.Handle, meta.Name))
+		}
+		existing, err := openMetadata(meta)
+		if err != nil {
+			return nil, codedError(ErrCodeShutdown, fmt.Errorf("opening instance %s to replace: %w", meta.Name, err))
+		}
+		// the replaced instance is removed outright, even if another process launched it in the foreground
+		existing.owned = true
+		return existing, nil
+	}
+	return nil, nil
+}
+
+// codes err, reporting ErrCodeInterrupted rather than code when the failure was caused by ctx being cancelled
+func launchError(ctx context.Context, code ErrorCode, err error) error {
+	if ctx.Err() != nil {
+		code = ErrCodeInterrupted
+	}
+	return codedError(code, err)
+}
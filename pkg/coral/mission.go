@@ -0,0 +1,215 @@
+package coral
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"coral_cli/internal/docker"
+	"coral_cli/internal/health"
+	"coral_cli/internal/libs"
+	"coral_cli/internal/logging"
+	"coral_cli/internal/registry"
+	"coral_cli/internal/util"
+)
+
+// options for Launcher.Mission
+type MissionOptions struct {
+	ComposePath      string        // optional compose file whose executors are merged into the instance (without starting them) before the mission runs
+	EnvFile          string        // optional .env file for compose substitutions of ComposePath
+	SkipVersionCheck bool          // skip the coral.version compatibility check for the images of ComposePath
+	StopOnFailure    bool          // skip the remaining executors once one exits non-zero, times out or fails to start
+	Timeout          time.Duration // how long each executor may run before it is killed and counted as failed; unbounded when zero
+	Follow           bool          // stream each executor's logs while it runs
+	Progress         func(MissionStep)
+}
+
+// the outcome of one executor run by Launcher.Mission
+type MissionStep struct {
+	Executor        string        `json:"executor"`
+	StartedAt       string        `json:"started_at,omitempty"`
+	DurationSeconds float64       `json:"duration_seconds"`
+	ExitCode        int           `json:"exit_code"` // -1 when the executor did not run to completion
+	TimedOut        bool          `json:"timed_out,omitempty"`
+	Skipped         bool          `json:"skipped,omitempty"` // not run because an earlier executor failed and StopOnFailure was set
+	Error           string        `json:"error,omitempty"`
+	Injected        []InjectedLib `json:"injected,omitempty"`
+}
+
+// reports whether the executor ran and exited zero
+func (s MissionStep) Succeeded() bool {
+	return !s.Skipped && s.Error == "" && s.ExitCode == 0
+}
+
+// the executors run by Launcher.Mission, in order
+type MissionResult struct {
+	Instance string        `json:"instance"`
+	Steps    []MissionStep `json:"steps"`
+}
+
+// reports whether every executor ran and exited zero
+func (r *MissionResult) Succeeded() bool {
+	for _, step := range r.Steps {
+		if !step.Succeeded() {
+			return false
+		}
+	}
+	return true
+}
+
+// runs the given executors of a running instance one after another, leaving its drivers and skillsets untouched: each is created, injected with libraries and started (replacing the container of any earlier run), then waited on until it exits. A failed executor is recorded and the next one run, unless StopOnFailure is set. Cancelling ctx stops the running executor and skips the rest. The result is returned along with any error, including for executors that exited non-zero
+func (l *Launcher) Mission(ctx context.Context, inst *Instance, executors []string, opts MissionOptions) (*MissionResult, error) {
+	if len(executors) == 0 {
+		return nil, codedError(ErrCodeInvalidInput, fmt.Errorf("no executors given"))
+	}
+	reg, err := registry.Load(inst.LibPath)
+	if err != nil {
+		return nil, fmt.Errorf("loading registry: %w", err)
+	}
+	if opts.ComposePath != "" {
+		if err := l.mergeMissionExecutors(ctx, inst, opts, reg); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range executors {
+		if !slices.Contains(inst.profilesMap["executors"], name) {
+			return nil, codedError(ErrCodeNotFound, fmt.Errorf("%s has no executor %s (executors: %s)", inst.Name, name, strings.Join(inst.profilesMap["executors"], ", ")))
+		}
+	}
+
+	result := &MissionResult{Instance: inst.Name}
+	// steps often run the same executor, or executors taking the same libraries, so their archives are shared
+	cache := libs.NewArchiveCache()
+	defer cache.Close()
+	failed := false
+	for _, name := range executors {
+		step := MissionStep{Executor: name, ExitCode: -1}
+		switch {
+		case ctx.Err() != nil, failed && opts.StopOnFailure:
+			step.Skipped = true
+		default:
+			step = runMissionStep(ctx, inst, name, reg, cache, opts)
+			failed = failed || !step.Succeeded()
+		}
+		result.Steps = append(result.Steps, step)
+		if opts.Progress != nil {
+			opts.Progress(step)
+		}
+	}
+
+	if ctx.Err() != nil {
+		return result, codedError(ErrCodeInterrupted, ctx.Err())
+	}
+	if !result.Succeeded() {
+		return result, codedError(ErrCodeMission, fmt.Errorf("mission failed"))
+	}
+	return result, nil
+}
+
+// merges the executors of opts.ComposePath into the instance, rejecting drivers and skillsets
+func (l *Launcher) mergeMissionExecutors(ctx context.Context, inst *Instance, opts MissionOptions, reg *registry.Registry) error {
+	cf, env, err := loadCompose(LaunchOptions{ComposePath: opts.ComposePath, EnvFile: opts.EnvFile})
+	if err != nil {
+		return err
+	}
+	hostLibPath, err := hostLib(env)
+	if err != nil {
+		return err
+	}
+	if err := checkImagesLocal(ctx, cf, l.Version, opts.SkipVersionCheck); err != nil {
+		return launchError(ctx, ErrCodeImage, fmt.Errorf("checking images: %w", err))
+	}
+	for name, svc := range cf.Services {
+		// executors from earlier missions are already part of the instance and are reused as they are
+		if slices.Contains(inst.profilesMap["executors"], name) {
+			delete(cf.Services, name)
+			continue
+		}
+		image, _ := svc["image"].(string)
+		labels, err := libs.GetImageLabels(ctx, image)
+		if err != nil {
+			return launchError(ctx, ErrCodeImage, fmt.Errorf("reading labels for service %s: %w", name, err))
+		}
+		if labels["coral.profile"] != "executors" {
+			return codedError(ErrCodeInvalidInput, fmt.Errorf("service %s is not an executor: use coral add to start drivers and skillsets", name))
+		}
+	}
+	if len(cf.Services) == 0 {
+		return nil
+	}
+	_, err = inst.mergeServices(ctx, cf, hostLibPath, reg)
+	return err
+}
+
+// creates, injects and starts one executor and waits for it to exit
+func runMissionStep(ctx context.Context, inst *Instance, name string, reg *registry.Registry, cache *libs.ArchiveCache, opts MissionOptions) MissionStep {
+	step := MissionStep{Executor: name, ExitCode: -1}
+	fail := func(err error) MissionStep {
+		step.Error = err.Error()
+		logging.Failuref("Executor %s: %v", logging.BoldMagenta(name), err)
+		return step
+	}
+
+	// a container left from an earlier run is replaced so the executor starts from a fresh filesystem
+	if containerID, _ := health.GetContainerIDForService(ctx, inst.Name, name); containerID != "" {
+		if err := removeServices(ctx, inst.Name, inst.ComposeFile, "executors", []string{name}, reg); err != nil {
+			return fail(err)
+		}
+	}
+
+	logging.Infof("Running executor %s", logging.BoldMagenta(name))
+	started := time.Now()
+	step.StartedAt = started.Format(time.RFC3339)
+	injected, err := createAndStartExecutors(ctx, inst.Name, inst.ComposeFile, []string{name}, reg, cache)
+	step.Injected = injected[name]
+	if err != nil {
+		return fail(fmt.Errorf("starting: %w", err))
+	}
+	containerID, err := health.GetContainerIDForService(ctx, inst.Name, name)
+	if err != nil {
+		return fail(fmt.Errorf("locating container: %w", err))
+	}
+	if containerID == "" {
+		return fail(fmt.Errorf("no container for executor service %s", name))
+	}
+
+	waitCtx := ctx
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	var logsDone <-chan struct{}
+	if opts.Follow {
+		logsDone, _ = docker.TailLogs([]util.ContainerInfo{{ID: containerID, Name: name, Service: name}}, waitCtx.Done(), true)
+	}
+
+	code, err := waitContainer(waitCtx, containerID)
+	step.DurationSeconds = time.Since(started).Seconds()
+	if logsDone != nil {
+		// let the last lines of the executor's output through before reporting on it
+		select {
+		case <-logsDone:
+		case <-time.After(5 * time.Second):
+		}
+	}
+	if err != nil {
+		if waitCtx.Err() == nil {
+			return fail(fmt.Errorf("waiting for exit: %w", err))
+		}
+		// the executor is still running; it must not outlive its slot in the mission
+		if stopErr := docker.CommandContext(context.Background(), docker.Compose, "kill", containerID).Run(); stopErr != nil {
+			logging.Warnf("Killing executor %s: %v", name, stopErr)
+		}
+		if errors.Is(waitCtx.Err(), context.DeadlineExceeded) {
+			step.TimedOut = true
+			return fail(fmt.Errorf("still running after %s", opts.Timeout))
+		}
+		return fail(fmt.Errorf("interrupted"))
+	}
+	step.ExitCode = code
+	return step
+}
@@ -0,0 +1,359 @@
+package coral
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"coral_cli/internal/compose"
+	"coral_cli/internal/docker"
+	"coral_cli/internal/health"
+	"coral_cli/internal/libs"
+	"coral_cli/internal/logging"
+	"coral_cli/internal/registry"
+)
+
+// what Launcher.Reconcile changed, by service name
+type ReconcileResult struct {
+	Instance   string                   `json:"instance"`
+	Added      []string                 `json:"added"`      // services started because they were new
+	Removed    []string                 `json:"removed"`    // services stopped and removed because they were dropped
+	Recreated  []string                 `json:"recreated"`  // services whose config or image changed, or executors whose libraries were withdrawn or replaced
+	Reinjected []string                 `json:"reinjected"` // running executors that were given new libraries
+	Unchanged  []string                 `json:"unchanged"`
+	Injected   map[string][]InjectedLib `json:"injected,omitempty"` // libraries injected into each created, recreated or reinjected executor
+}
+
+// reports whether Reconcile found nothing to do
+func (r *ReconcileResult) Empty() bool {
+	return len(r.Added) == 0 && len(r.Removed) == 0 && len(r.Recreated) == 0 && len(r.Reinjected) == 0
+}
+
+// brings a running instance in line with the compose file named by opts (only ComposePath, EnvFile, HealthTimeout and SkipVersionCheck are used) by applying just the differences to its merged compose: services that were added are started, services that were removed are stopped and removed, and services whose config or image changed are recreated, drivers and skillsets before executors. Executors that are otherwise unchanged but whose set of compatible libraries changed have the new libraries injected while they run, or are recreated when libraries they had were withdrawn or replaced, since a running executor keeps the libraries it already loaded. Nothing is rolled back on failure
+func (l *Launcher) Reconcile(ctx context.Context, inst *Instance, opts LaunchOptions) (*ReconcileResult, error) {
+	cf, env, err := loadCompose(opts)
+	if err != nil {
+		return nil, err
+	}
+	hostLibPath, err := hostLib(env)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkImagesLocal(ctx, cf, l.Version, opts.SkipVersionCheck); err != nil {
+		return nil, launchError(ctx, ErrCodeImage, fmt.Errorf("checking images: %w", err))
+	}
+	reg, err := registry.Load(inst.LibPath)
+	if err != nil {
+		return nil, fmt.Errorf("loading registry: %w", err)
+	}
+	current, err := compose.LoadRawYAML(inst.ComposeFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading merged compose of %s: %w", inst.Name, err)
+	}
+	currentServices, _ := current["services"].(map[string]interface{})
+
+	referenced := extractionsOf(reg, inst.Name)
+	desired, profilesMap, extractions, err := buildMergedCompose(ctx, cf, inst.LibPath, hostLibPath, nil, inst.Name, reg)
+	if err != nil {
+		return nil, launchError(ctx, ErrCodeExtraction, err)
+	}
+	desiredServices := desired["services"].(map[string]interface{})
+	for _, svc := range desiredServices {
+		compose.SetLabels(svc.(map[string]interface{}), inst.Labels)
+	}
+
+	result := &ReconcileResult{Instance: inst.Name, Injected: map[string][]InjectedLib{}}
+	changed := map[string][]string{} // profile → services to create or recreate
+	recreate := map[string]bool{}
+	for _, profile := range orderedProfiles(extractProfileNames(profilesMap)) {
+		for _, name := range profilesMap[profile] {
+			currentSvc, exists := currentServices[name]
+			if !exists {
+				result.Added = append(result.Added, name)
+				changed[profile] = append(changed[profile], name)
+				continue
+			}
+			differs, err := serviceChanged(ctx, inst.Name, name, currentSvc, desiredServices[name])
+			if err != nil {
+				return nil, launchError(ctx, ErrCodeUnknown, err)
+			}
+			if differs {
+				result.Recreated = append(result.Recreated, name)
+				changed[profile] = append(changed[profile], name)
+				recreate[name] = true
+				continue
+			}
+			result.Unchanged = append(result.Unchanged, name)
+		}
+	}
+	removed := map[string][]string{} // profile → services
+	for name, svc := range currentServices {
+		if _, kept := desiredServices[name]; !kept {
+			result.Removed = append(result.Removed, name)
+			removed[serviceProfile(svc)] = append(removed[serviceProfile(svc)], name)
+		}
+	}
+	slices.Sort(result.Removed)
+
+	// dropped services go first, in reverse start order, while the current compose file still describes them
+	for _, profile := range slices.Backward(orderedProfiles(extractProfileNames(removed))) {
+		logging.Infof("Removing %s (%d): %s", logging.BoldMagenta(profile), len(removed[profile]), logging.BoldMagenta(fmt.Sprintf("%v", removed[profile])))
+		if err := removeServices(ctx, inst.Name, inst.ComposeFile, profile, removed[profile], reg); err != nil {
+			return result, launchError(ctx, ErrCodeShutdown, err)
+		}
+	}
+	releaseExtractions(reg, inst.Name, referenced, extractions)
+
+	if err := writeComposeToDisk(inst.ComposeFile, desired); err != nil {
+		return result, err
+	}
+
+	var started []string
+	for _, profile := range []string{"drivers", "skillsets"} {
+		if len(changed[profile]) == 0 {
+			continue
+		}
+		logging.Infof("Updating %s (%d): %s", logging.BoldMagenta(profile), len(changed[profile]), logging.BoldMagenta(fmt.Sprintf("%v", changed[profile])))
+		args := []string{"compose", "-p", inst.Name, "-f", inst.ComposeFile, "--profile", profile, "up", "-d", "--no-deps", "--force-recreate"}
+		cmd := docker.CommandContext(ctx, docker.Compose, append(args, changed[profile]...)...)
+		cmd.Stdout = logging.CommandOutput()
+		cmd.Stderr = logging.CommandErrors()
+		if err := cmd.Run(); err != nil {
+			return result, launchError(ctx, ErrCodeStart, fmt.Errorf("starting %s: %w", profile, err))
+		}
+		started = append(started, changed[profile]...)
+	}
+	if len(started) > 0 && len(profilesMap["executors"]) > 0 {
+		logging.Infof("Waiting for updated drivers and skillsets to become healthy...")
+		if err := health.WaitForHealthy(ctx, inst.Name, started, opts.HealthTimeout); err != nil {
+			if ctx.Err() != nil {
+				return result, launchError(ctx, ErrCodeInterrupted, err)
+			}
+			logging.Warnf("Health gate timed out: %v — proceeding anyway", err)
+		}
+	}
+
+	// unchanged executors keep running if they only gain libraries, and are recreated if libraries they had were withdrawn or replaced
+	var reinject []string
+	for _, name := range profilesMap["executors"] {
+		if slices.Contains(changed["executors"], name) {
+			continue
+		}
+		replaced, gained, err := libraryChanges(ctx, inst.Name, name, reg)
+		if err != nil {
+			return result, launchError(ctx, ErrCodeUnknown, err)
+		}
+		switch {
+		case replaced:
+			result.Unchanged = slices.DeleteFunc(result.Unchanged, func(s string) bool { return s == name })
+			result.Recreated = append(result.Recreated, name)
+			changed["executors"] = append(changed["executors"], name)
+			recreate[name] = true
+		case gained:
+			result.Unchanged = slices.DeleteFunc(result.Unchanged, func(s string) bool { return s == name })
+			reinject = append(reinject, name)
+		}
+	}
+
+	cache := libs.NewArchiveCache()
+	defer cache.Close()
+	if execs := changed["executors"]; len(execs) > 0 {
+		var stale []string
+		for _, name := range execs {
+			if recreate[name] {
+				stale = append(stale, name)
+			}
+		}
+		if len(stale) > 0 {
+			if err := removeServices(ctx, inst.Name, inst.ComposeFile, "executors", stale, reg); err != nil {
+				return result, launchError(ctx, ErrCodeShutdown, err)
+			}
+		}
+		logging.Infof("Updating %s (%d): %s", logging.BoldMagenta("executors"), len(execs), logging.BoldMagenta(fmt.Sprintf("%v", execs)))
+		injected, err := createAndStartExecutors(ctx, inst.Name, inst.ComposeFile, execs, reg, cache)
+		if err != nil {
+			return result, launchError(ctx, ErrCodeStart, fmt.Errorf("starting executors: %w", err))
+		}
+		for name, libs := range injected {
+			result.Injected[name] = libs
+		}
+	}
+	for _, name := range reinject {
+		injected, err := injectExecutor(ctx, inst.Name, name, reg, cache)
+		if err != nil {
+			return result, launchError(ctx, ErrCodeStart, err)
+		}
+		result.Injected[name] = injected
+		result.Reinjected = append(result.Reinjected, name)
+	}
+
+	inst.profilesMap = profilesMap
+	inst.profiles = orderedProfiles(extractProfileNames(profilesMap))
+	inst.reg = reg
+	return result, nil
+}
+
+// reports whether a service's merged config differs between the current and desired compose, or its running container uses a different image than the one its tag now refers to
+func serviceChanged(ctx context.Context, instanceName, name string, current, desired interface{}) (bool, error) {
+	a, err := normalizeService(current)
+	if err != nil {
+		return false, err
+	}
+	b, err := normalizeService(desired)
+	if err != nil {
+		return false, err
+	}
+	if !reflect.DeepEqual(a, b) {
+		return true, nil
+	}
+	containerID, err := health.GetContainerIDForService(ctx, instanceName, name)
+	if err != nil {
+		return false, fmt.Errorf("locating container for %s: %w", name, err)
+	}
+	if containerID == "" {
+		return true, nil
+	}
+	out, err := docker.CommandContext(ctx, docker.Query, "inspect", "--format", "{{.Image}}", containerID).Output()
+	if err != nil {
+		return false, fmt.Errorf("inspecting container for %s: %w", name, err)
+	}
+	image, _ := b["image"].(string)
+	imageID, err := libs.GetImageID(ctx, image)
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(string(out)) != imageID, nil
+}
+
+// round-trips a service definition through YAML so configs built in memory compare equal to those read back from disk
+func normalizeService(svc interface{}) (map[string]interface{}, error) {
+	data, err := yaml.Marshal(svc)
+	if err != nil {
+		return nil, err
+	}
+	var normalized map[string]interface{}
+	if err := yaml.Unmarshal(data, &normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+// the Coral profile a merged compose service was written with
+func serviceProfile(svc interface{}) string {
+	m, _ := svc.(map[string]interface{})
+	profiles, _ := m["profiles"].([]interface{})
+	if len(profiles) == 0 {
+		return ""
+	}
+	profile, _ := profiles[0].(string)
+	return profile
+}
+
+// stops and removes the containers of the given services, dropping the injection records of any executors among them
+func removeServices(ctx context.Context, instanceName, composePath, profile string, services []string, reg *registry.Registry) error {
+	for _, name := range services {
+		if containerID, _ := health.GetContainerIDForService(ctx, instanceName, name); containerID != "" {
+			if err := reg.RemoveInjection(containerID); err != nil {
+				logging.Warnf("Removing injection record for %s: %v", name, err)
+			}
+		}
+	}
+	args := []string{"compose", "-p", instanceName, "-f", composePath, "--profile", profile, "rm", "--stop", "--force"}
+	cmd := docker.CommandContext(ctx, docker.Compose, append(args, services...)...)
+	cmd.Stdout = logging.CommandOutput()
+	cmd.Stderr = logging.CommandErrors()
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("removing %s: %w", strings.Join(services, ", "), err)
+	}
+	return nil
+}
+
+// returns the registry keys of every extraction the instance holds a reference to
+func extractionsOf(reg *registry.Registry, instanceName string) []string {
+	var keys []string
+	for key, rec := range reg.AllExtractions() {
+		if slices.Contains(rec.InstanceIDs, instanceName) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// drops the instance's reference to every extraction it held before reconciling that no desired service uses any more, deleting staging directories no other instance holds
+func releaseExtractions(reg *registry.Registry, instanceName string, before []string, used map[string]string) {
+	inUse := make(map[string]bool, len(used))
+	for _, key := range used {
+		inUse[key] = true
+	}
+	for _, key := range before {
+		if inUse[key] {
+			continue
+		}
+		dir, err := reg.RemoveExtraction(key, instanceName)
+		if err != nil {
+			logging.Warnf("Releasing extraction %s: %v", key, err)
+			continue
+		}
+		if dir != "" {
+			if err := os.RemoveAll(dir); err != nil {
+				logging.Warnf("Removing staging dir %s: %v", dir, err)
+			}
+		}
+	}
+}
+
+// compares the libraries recorded as injected into a running executor with those that would be injected now: replaced reports that a library it has is no longer provided, or now comes from a different payload with different content, and gained that a library name is new
+func libraryChanges(ctx context.Context, instanceName, svc string, reg *registry.Registry) (replaced, gained bool, err error) {
+	containerID, sources, policy, err := executorLibraries(ctx, instanceName, svc, reg)
+	if err != nil {
+		return false, false, err
+	}
+	platform, err := libs.GetContainerPlatform(ctx, containerID)
+	if err != nil {
+		return false, false, fmt.Errorf("reading platform of %s: %w", svc, err)
+	}
+	planned, err := libs.PlanLibraries(sources, policy.conflicts, platform)
+	if err != nil {
+		return false, false, fmt.Errorf("planning libraries for %s: %w", svc, err)
+	}
+	want := activeLibs(planned)
+	have := activeLibs(reg.AllInjections()[containerID].Libs)
+	for file, had := range have {
+		lib, ok := want[file]
+		switch {
+		case !ok:
+			replaced = true
+		case lib.PayloadID != had.PayloadID:
+			// another payload's identical file needs no restart; one injected before checksums were recorded is assumed to differ
+			sum, err := libs.LibrarySHA256(sources, lib)
+			if err != nil {
+				return false, false, fmt.Errorf("hashing %s/%s for %s: %w", lib.SubDir, lib.LibName, svc, err)
+			}
+			if had.SHA256 == "" || sum != had.SHA256 {
+				replaced = true
+			}
+		}
+	}
+	for file := range want {
+		if _, ok := have[file]; !ok {
+			gained = true
+		}
+	}
+	return replaced, gained, nil
+}
+
+// maps subdir/name of every library that won its conflict to its record
+func activeLibs(injected []InjectedLib) map[string]InjectedLib {
+	active := make(map[string]InjectedLib)
+	for _, lib := range injected {
+		if !lib.Shadowed && lib.Rejected == "" {
+			active[lib.SubDir+"/"+lib.LibName] = lib
+		}
+	}
+	return active
+}
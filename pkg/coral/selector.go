@@ -0,0 +1,152 @@
+package coral
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"coral_cli/internal/docker"
+	"coral_cli/internal/logging"
+	"coral_cli/internal/util"
+)
+
+// picks instances by name, handle, group, label and age. Every criterion that is set must match, while several values given for one criterion match if any of them does. Names, handles and groups may be glob patterns (e.g. robot-*)
+type Selector struct {
+	Names     []string
+	Handles   []string
+	Groups    []string
+	Labels    map[string]string // labels that must all have the given values, either among the instance's own labels or on one of its containers
+	OlderThan time.Duration     // only instances created at least this long ago; any age when zero
+}
+
+// reports whether no criteria are set, in which case every instance is selected
+func (s Selector) Empty() bool {
+	return len(s.Names) == 0 && len(s.Handles) == 0 && len(s.Groups) == 0 && len(s.Labels) == 0 && s.OlderThan == 0
+}
+
+// rejects malformed glob patterns
+func (s Selector) Validate() error {
+	for _, pattern := range slices.Concat(s.Names, s.Handles, s.Groups) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return codedError(ErrCodeInvalidInput, fmt.Errorf("invalid pattern %q: %w", pattern, err))
+		}
+	}
+	return nil
+}
+
+// reports whether meta satisfies every criterion except Labels, which may need the instance's containers and is checked by selectMetadata
+func (s Selector) matches(meta util.InstanceMetadata, now time.Time) bool {
+	if len(s.Names) > 0 && !matchAny(s.Names, meta.Name) {
+		return false
+	}
+	if len(s.Handles) > 0 && !matchAny(s.Handles, meta.Handle) {
+		return false
+	}
+	if len(s.Groups) > 0 && !matchAny(s.Groups, meta.Group) {
+		return false
+	}
+	if s.OlderThan > 0 {
+		created, err := time.Parse(time.RFC3339, meta.CreatedAt)
+		if err != nil || now.Sub(created) < s.OlderThan {
+			return false
+		}
+	}
+	return true
+}
+
+func matchAny(patterns []string, value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, value); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// returns handles to every instance matched by s (every known instance when s is empty)
+func Select(ctx context.Context, s Selector) ([]*Instance, error) {
+	metas, err := selectMetadata(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	var instances []*Instance
+	for _, meta := range metas {
+		inst, err := openMetadata(meta)
+		if err != nil {
+			logging.Warnf("Skipping %s: %v", meta.Name, err)
+			continue
+		}
+		instances = append(instances, inst)
+	}
+	return instances, nil
+}
+
+// returns the metadata of every instance matched by s
+func selectMetadata(ctx context.Context, s Selector) ([]util.InstanceMetadata, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	metadataList, err := util.LoadAllMetadata()
+	if err != nil {
+		return nil, fmt.Errorf("loading metadata: %w", err)
+	}
+	// container labels are only looked up when an instance's own labels do not already match
+	var labelled []string
+	lookedUp := false
+	now := time.Now()
+	var selected []util.InstanceMetadata
+	for _, meta := range metadataList {
+		if !s.matches(meta, now) {
+			continue
+		}
+		if len(s.Labels) > 0 && !hasLabels(meta.Labels, s.Labels) {
+			if !lookedUp {
+				if labelled, err = labelledProjects(ctx, s.Labels); err != nil {
+					return nil, codedError(ErrCodeUnknown, fmt.Errorf("matching labels: %w", err))
+				}
+				lookedUp = true
+			}
+			if !slices.Contains(labelled, meta.Name) {
+				continue
+			}
+		}
+		selected = append(selected, meta)
+	}
+	return selected, nil
+}
+
+// reports whether every wanted label is set to its value in labels
+func hasLabels(labels, wanted map[string]string) bool {
+	for key, value := range wanted {
+		if v, ok := labels[key]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+// returns the compose projects with at least one container carrying every given label
+func labelledProjects(ctx context.Context, labels map[string]string) ([]string, error) {
+	args := []string{"ps", "-a", "--filter", "label=com.docker.compose.project"}
+	for key, value := range labels {
+		args = append(args, "--filter", fmt.Sprintf("label=%s=%s", key, value))
+	}
+	args = append(args, "--format", `{{.Label "com.docker.compose.project"}}`)
+	out, err := docker.CommandContext(ctx, docker.Query, args...).Output()
+	if err != nil {
+		return nil, err
+	}
+	var projects []string
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !slices.Contains(projects, line) {
+			projects = append(projects, line)
+		}
+	}
+	return projects, nil
+}
@@ -0,0 +1,203 @@
+package coral
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"coral_cli/internal/bt"
+	"coral_cli/internal/libs"
+	"coral_cli/internal/logging"
+	"coral_cli/internal/registry"
+	"coral_cli/internal/util"
+)
+
+// a node of a behavior tree that the injected behaviors cannot instantiate as written
+type TreeProblem = bt.Problem
+
+// options for Launcher.ValidateTree
+type TreeOptions struct {
+	ComposePath      string         // compose file whose payloads would be injected; resolved like docker compose when empty
+	EnvFile          string         // optional .env file for compose substitutions; ./.env is used when empty and present
+	Executor         string         // executor service the tree is checked for; the compose file's only executor when empty
+	ConflictPolicy   ConflictPolicy // how libraries with the same name from different payloads are resolved; ConflictPriority when empty
+	SkipVersionCheck bool           // skip the coral.version compatibility check between the launcher and images
+}
+
+// the outcome of Launcher.ValidateTree
+type TreeValidation struct {
+	Tree     string        `json:"tree"`
+	Executor string        `json:"executor"`
+	Nodes    []string      `json:"nodes"`              // nodes the executor's injected behaviors would provide
+	Unlisted []string      `json:"unlisted,omitempty"` // payloads whose behaviors would be injected without a node manifest, so their nodes are unknown
+	Problems []TreeProblem `json:"problems"`
+	Unknown  []TreeProblem `json:"unknown,omitempty"` // nodes no manifest declares, which a payload in Unlisted may still provide; they do not fail validation
+}
+
+// checks a BT.CPP XML file against the nodes an executor of a compose file would be given: every payload of the compose file is extracted into a temporary directory, the libraries the executor would receive are planned as a launch would, and each node of the tree must be built into BT.CPP or declared in the manifest of a payload whose behaviors would be injected, setting only the ports declared for it. Nothing is launched. The validation is returned along with an ErrCodeInvalidTree error when it found problems
+func (l *Launcher) ValidateTree(ctx context.Context, treePath string, opts TreeOptions) (*TreeValidation, error) {
+	if opts.ConflictPolicy == "" {
+		opts.ConflictPolicy = ConflictPriority
+	}
+	if !opts.ConflictPolicy.Valid() {
+		return nil, codedError(ErrCodeInvalidInput, fmt.Errorf("invalid conflict policy %q: must be error, priority or newest", opts.ConflictPolicy))
+	}
+	raw, err := os.ReadFile(treePath)
+	if err != nil {
+		return nil, codedError(ErrCodeInvalidInput, fmt.Errorf("reading behavior tree: %w", err))
+	}
+	doc, err := bt.Parse(bytes.NewReader(raw))
+	if err != nil {
+		return nil, codedError(ErrCodeInvalidTree, fmt.Errorf("parsing %s: %w", treePath, err))
+	}
+
+	cf, _, err := loadCompose(LaunchOptions{ComposePath: opts.ComposePath, EnvFile: opts.EnvFile})
+	if err != nil {
+		return nil, err
+	}
+	composePath, err := util.ResolveComposeFile(opts.ComposePath)
+	if err != nil {
+		return nil, codedError(ErrCodeInvalidInput, err)
+	}
+	if err := checkImagesLocal(ctx, cf, l.Version, opts.SkipVersionCheck); err != nil {
+		return nil, launchError(ctx, ErrCodeImage, fmt.Errorf("checking images: %w", err))
+	}
+
+	// payloads are extracted into a temp lib dir so validation leaves no lasting state
+	tmpLib, err := os.MkdirTemp("", "coral-bt-*")
+	if err != nil {
+		return nil, fmt.Errorf("creating temp lib dir: %w", err)
+	}
+	defer os.RemoveAll(tmpLib)
+
+	extractions := make(map[string]registry.ExtractionRecord)
+	var executors []string
+	for _, name := range slices.Sorted(maps.Keys(cf.Services)) {
+		svc := cf.Services[name]
+		image := svc["image"].(string) // already validated in checkImagesLocal
+		labels, err := libs.GetImageLabels(ctx, image)
+		if err != nil {
+			return nil, launchError(ctx, ErrCodeImage, fmt.Errorf("reading labels for service %s: %w", name, err))
+		}
+		if labels["coral.profile"] == "executors" {
+			executors = append(executors, name)
+		}
+		stagingDir, imageID, err := libs.ExtractLibraries(ctx, image, name, tmpLib)
+		if err != nil {
+			return nil, launchError(ctx, ErrCodeExtraction, fmt.Errorf("extracting %s for service %s: %w", image, name, err))
+		}
+		priority, err := libraryPriority(labels, svc)
+		if err != nil {
+			return nil, codedError(ErrCodeInvalidInput, fmt.Errorf("service %s: %w", name, err))
+		}
+		extractions[imageID] = registry.ExtractionRecord{
+			ImageID:      imageID,
+			StagingDir:   stagingDir,
+			PayloadID:    imageID,
+			BtcppVersion: labels["coral.btcpp_version"],
+			RosDistro:    labels["coral.ros_distro"],
+			Priority:     priority,
+			Services:     []string{name},
+			Images:       []string{image},
+		}
+	}
+
+	executor := opts.Executor
+	switch {
+	case executor != "" && !slices.Contains(executors, executor):
+		return nil, codedError(ErrCodeInvalidInput, fmt.Errorf("service %s is not an executor of %s", executor, composePath))
+	case executor == "" && len(executors) != 1:
+		return nil, codedError(ErrCodeInvalidInput, fmt.Errorf("%s has %d executors: name the one to check with --executor", composePath, len(executors)))
+	case executor == "":
+		executor = executors[0]
+	}
+	image := cf.Services[executor]["image"].(string)
+	execLabels, err := libs.GetImageLabels(ctx, image)
+	if err != nil {
+		return nil, launchError(ctx, ErrCodeImage, fmt.Errorf("reading labels for executor %s: %w", executor, err))
+	}
+	platform, err := libs.GetImagePlatform(ctx, image)
+	if err != nil {
+		return nil, launchError(ctx, ErrCodeImage, err)
+	}
+	filter, err := executorImportFilter(composePath, executor, execLabels)
+	if err != nil {
+		return nil, codedError(ErrCodeInvalidInput, fmt.Errorf("reading import filter of executor %s: %w", executor, err))
+	}
+	sources, err := acceptedPayloads(ctx, executor, execLabels, filter, extractions, injectionPolicy{scope: ScopeGlobal})
+	if err != nil {
+		return nil, err
+	}
+	planned, err := libs.PlanLibraries(sources, opts.ConflictPolicy, platform)
+	if err != nil {
+		return nil, codedError(ErrCodeConflict, err)
+	}
+	nodes, unlisted, err := libs.InjectedNodes(sources, planned)
+	if err != nil {
+		return nil, codedError(ErrCodeExtraction, err)
+	}
+
+	result := &TreeValidation{
+		Tree:     treePath,
+		Executor: executor,
+		Nodes:    slices.Sorted(maps.Keys(nodes)),
+		Problems: []TreeProblem{},
+	}
+	for _, payloadID := range unlisted {
+		result.Unlisted = append(result.Unlisted, payloadName(extractions[payloadID]))
+	}
+	for _, problem := range bt.Validate(doc, nodes, len(unlisted) > 0) {
+		if problem.Unknown {
+			result.Unknown = append(result.Unknown, problem)
+		} else {
+			result.Problems = append(result.Problems, problem)
+		}
+	}
+	if len(result.Problems) > 0 {
+		return result, codedError(ErrCodeInvalidTree, fmt.Errorf("%d problems in %s", len(result.Problems), treePath))
+	}
+	return result, nil
+}
+
+// checks the behavior tree named by BT_FILE in an executor's container against the nodes of the libraries just injected into it, reporting every problem; an executor without BT_FILE, or whose tree cannot be read, is not checked
+func checkExecutorTree(ctx context.Context, containerID, svc string, sources map[string]libs.LibrarySource, injected []registry.InjectedLib) {
+	treePath, err := libs.ReadContainerEnv(ctx, containerID, "BT_FILE")
+	if err != nil || treePath == "" {
+		return
+	}
+	raw, err := libs.ReadContainerFile(ctx, containerID, treePath)
+	if err != nil {
+		logging.Warnf("Not checking the behavior tree of executor %s: %v", svc, err)
+		return
+	}
+	doc, err := bt.Parse(bytes.NewReader(raw))
+	if err != nil {
+		logging.Failuref("Behavior tree %s of executor %s cannot be parsed: %v", treePath, svc, err)
+		return
+	}
+	nodes, unlisted, err := libs.InjectedNodes(sources, injected)
+	if err != nil {
+		logging.Warnf("Not checking the behavior tree of executor %s: %v", svc, err)
+		return
+	}
+	problems := bt.Validate(doc, nodes, len(unlisted) > 0)
+	unknown := 0
+	for _, problem := range problems {
+		if problem.Unknown {
+			logging.Warnf("Behavior tree %s of executor %s, %s", treePath, svc, problem)
+			unknown++
+		} else {
+			logging.Failuref("Behavior tree %s of executor %s, %s", treePath, svc, problem)
+		}
+	}
+	if unknown > 0 {
+		logging.Warnf("Payloads without a node manifest may provide the undeclared nodes: %s", strings.Join(unlisted, ", "))
+	}
+	if len(problems) == 0 {
+		logging.Infof("Behavior tree %s of executor %s uses only available nodes", treePath, logging.BoldMagenta(svc))
+	}
+}
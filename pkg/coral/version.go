@@ -0,0 +1,236 @@
+package coral
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// extracts the leading integer from a version string of the form [v]MAJOR[.MINOR[.PATCH[...]]].
+func parseMajorVersion(v string) (int, error) {
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexByte(v, '.'); i != -1 {
+		v = v[:i]
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse major version from %q", v)
+	}
+	return n, nil
+}
+
+// a semantic version; missing minor and patch numbers read as zero
+type semver struct {
+	major, minor, patch int
+	pre                 []string // pre-release identifiers, which sort before the release itself
+}
+
+// parses [v]MAJOR[.MINOR[.PATCH]][-PRERELEASE][+BUILD]; the number of components given is returned for partial matches
+func parseSemver(v string) (semver, int, error) {
+	s := strings.TrimPrefix(strings.TrimSpace(v), "v")
+	s, _, _ = strings.Cut(s, "+")
+	var ver semver
+	if core, pre, ok := strings.Cut(s, "-"); ok {
+		if pre == "" {
+			return semver{}, 0, fmt.Errorf("invalid version %q: empty pre-release", v)
+		}
+		ver.pre = strings.Split(pre, ".")
+		s = core
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return semver{}, 0, fmt.Errorf("invalid version %q: more than three components", v)
+	}
+	nums := []*int{&ver.major, &ver.minor, &ver.patch}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return semver{}, 0, fmt.Errorf("invalid version %q", v)
+		}
+		*nums[i] = n
+	}
+	return ver, len(parts), nil
+}
+
+func (v semver) compare(o semver) int {
+	for _, d := range []int{v.major - o.major, v.minor - o.minor, v.patch - o.patch} {
+		if d != 0 {
+			return d
+		}
+	}
+	switch {
+	case len(v.pre) == 0 && len(o.pre) == 0:
+		return 0
+	case len(v.pre) == 0:
+		return 1
+	case len(o.pre) == 0:
+		return -1
+	}
+	for i := 0; i < len(v.pre) && i < len(o.pre); i++ {
+		a, aErr := strconv.Atoi(v.pre[i])
+		b, bErr := strconv.Atoi(o.pre[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			if a != b {
+				return a - b
+			}
+		case aErr == nil: // numeric identifiers sort before alphanumeric ones
+			return -1
+		case bErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(v.pre[i], o.pre[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return len(v.pre) - len(o.pre)
+}
+
+// one comparison of a version range, e.g. >=4.6
+type versionConstraint struct {
+	text  string
+	check func(semver) bool
+}
+
+// a set of alternatives separated by ||, each a comma- or space-separated list of constraints that must all hold; a constraint is an operator (=, >, >=, <, <=, ~ or ^) and a version, a bare version (partial versions such as 4.6 match any 4.6.x) or *
+type versionRange struct {
+	alternatives [][]versionConstraint
+}
+
+func parseVersionRange(r string) (versionRange, error) {
+	var vr versionRange
+	for _, alt := range strings.Split(r, "||") {
+		var constraints []versionConstraint
+		fields := strings.FieldsFunc(alt, func(c rune) bool { return c == ',' || c == ' ' })
+		for i := 0; i < len(fields); i++ {
+			field := fields[i]
+			// an operator written apart from its version, as in ">= 4.6", applies to the version that follows
+			if strings.Trim(field, "=<>~^") == "" {
+				if i+1 == len(fields) {
+					return versionRange{}, fmt.Errorf("invalid version range %q: operator %q has no version", r, field)
+				}
+				i++
+				field += fields[i]
+			}
+			c, err := parseVersionConstraint(field)
+			if err != nil {
+				return versionRange{}, fmt.Errorf("invalid version range %q: %w", r, err)
+			}
+			constraints = append(constraints, c)
+		}
+		if len(constraints) == 0 {
+			return versionRange{}, fmt.Errorf("invalid version range %q: empty alternative", r)
+		}
+		vr.alternatives = append(vr.alternatives, constraints)
+	}
+	return vr, nil
+}
+
+func parseVersionConstraint(c string) (versionConstraint, error) {
+	op := c[:len(c)-len(strings.TrimLeft(c, "=<>~^"))]
+	v := strings.TrimPrefix(c, op)
+	if op == "" && (v == "*" || v == "x") {
+		return versionConstraint{text: c, check: func(semver) bool { return true }}, nil
+	}
+	v = strings.TrimSuffix(strings.TrimSuffix(v, ".x"), ".*")
+	bound, n, err := parseSemver(v)
+	if err != nil {
+		return versionConstraint{}, err
+	}
+	// the first version above every version matching the given components, e.g. 4.7.0 for 4.6
+	next := func(components int) semver {
+		switch components {
+		case 1:
+			return semver{major: bound.major + 1}
+		case 2:
+			return semver{major: bound.major, minor: bound.minor + 1}
+		}
+		return semver{major: bound.major, minor: bound.minor, patch: bound.patch + 1}
+	}
+	within := func(upper semver) func(semver) bool {
+		return func(s semver) bool { return s.compare(bound) >= 0 && s.compare(upper) < 0 }
+	}
+	var check func(semver) bool
+	switch op {
+	case "", "=", "==":
+		if n == 3 {
+			check = func(s semver) bool { return s.compare(bound) == 0 }
+		} else {
+			check = within(next(n))
+		}
+	case ">":
+		upper := next(n)
+		check = func(s semver) bool { return s.compare(upper) >= 0 }
+		if n == 3 {
+			check = func(s semver) bool { return s.compare(bound) > 0 }
+		}
+	case ">=":
+		check = func(s semver) bool { return s.compare(bound) >= 0 }
+	case "<":
+		check = func(s semver) bool { return s.compare(bound) < 0 }
+	case "<=":
+		upper := next(n)
+		check = func(s semver) bool { return s.compare(upper) < 0 }
+		if n == 3 {
+			check = func(s semver) bool { return s.compare(bound) <= 0 }
+		}
+	case "~":
+		// patch updates only, or minor updates when only the major version is given
+		check = within(next(min(n, 2)))
+	case "^":
+		// updates that do not change the leftmost non-zero component
+		switch {
+		case bound.major > 0 || n == 1:
+			check = within(next(1))
+		case bound.minor > 0 || n == 2:
+			check = within(next(2))
+		default:
+			check = within(next(3))
+		}
+	default:
+		return versionConstraint{}, fmt.Errorf("unknown operator %q in %q", op, c)
+	}
+	return versionConstraint{text: c, check: check}, nil
+}
+
+// reports whether v satisfies the range; if not, the constraints that failed are returned, one per alternative
+func (r versionRange) satisfiedBy(v semver) (bool, []string) {
+	var failed []string
+	for _, alt := range r.alternatives {
+		ok := true
+		for _, c := range alt {
+			if !c.check(v) {
+				failed = append(failed, c.text)
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, failed
+}
+
+// checks an executor's exact BT.CPP version against the version or range a payload declares; an empty reason means compatible. Versions that are not semantic versions must match exactly
+func btcppIncompatibility(payloadRange, execVersion string) string {
+	if payloadRange == execVersion {
+		return ""
+	}
+	if payloadRange == "" || execVersion == "" {
+		return fmt.Sprintf("BT.CPP version mismatch %q != %q", payloadRange, execVersion)
+	}
+	vr, err := parseVersionRange(payloadRange)
+	if err != nil {
+		return fmt.Sprintf("payload BT.CPP version: %v", err)
+	}
+	v, n, err := parseSemver(execVersion)
+	if err != nil || n != 3 {
+		return fmt.Sprintf("executor BT.CPP version %q is not an exact MAJOR.MINOR.PATCH version", execVersion)
+	}
+	if ok, failed := vr.satisfiedBy(v); !ok {
+		return fmt.Sprintf("BT.CPP %s is outside the payload's range %q (fails %s)", execVersion, payloadRange, strings.Join(failed, ", "))
+	}
+	return ""
+}
@@ -0,0 +1,106 @@
+package coral
+
+import "testing"
+
+func TestParseVersionConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		match      []string
+		reject     []string
+	}{
+		{constraint: "*", match: []string{"0.0.1", "4.6.2"}},
+		{constraint: "4.6.2", match: []string{"4.6.2"}, reject: []string{"4.6.3", "4.6.2-rc.1"}},
+		{constraint: "4.6", match: []string{"4.6.0", "4.6.9"}, reject: []string{"4.5.9", "4.7.0"}},
+		{constraint: "=4", match: []string{"4.0.0", "4.9.9"}, reject: []string{"3.9.9", "5.0.0"}},
+		{constraint: "4.x", match: []string{"4.2.0"}, reject: []string{"5.0.0"}},
+		{constraint: ">4.6", match: []string{"4.7.0"}, reject: []string{"4.6.9"}},
+		{constraint: ">4.6.1", match: []string{"4.6.2"}, reject: []string{"4.6.1"}},
+		{constraint: ">=4.6", match: []string{"4.6.0", "5.0.0"}, reject: []string{"4.5.9", "4.6.0-rc.1"}},
+		{constraint: "<4.6", match: []string{"4.5.9", "4.6.0-rc.1"}, reject: []string{"4.6.0"}},
+		{constraint: "<5", match: []string{"4.9.9", "5.0.0-alpha"}, reject: []string{"5.0.0"}},
+		{constraint: "<=4.6", match: []string{"4.6.9"}, reject: []string{"4.7.0"}},
+		{constraint: "<=4.6.1", match: []string{"4.6.1"}, reject: []string{"4.6.2"}},
+		{constraint: "~4.6.1", match: []string{"4.6.1", "4.6.9"}, reject: []string{"4.6.0", "4.7.0"}},
+		{constraint: "~4.6", match: []string{"4.6.0", "4.6.9"}, reject: []string{"4.7.0"}},
+		{constraint: "~4", match: []string{"4.0.0", "4.9.0"}, reject: []string{"5.0.0"}},
+		{constraint: "^4.6.1", match: []string{"4.6.1", "4.9.0"}, reject: []string{"4.6.0", "5.0.0"}},
+		{constraint: "^0.3.1", match: []string{"0.3.1", "0.3.9"}, reject: []string{"0.4.0", "0.3.0"}},
+		{constraint: "^0.0.3", match: []string{"0.0.3"}, reject: []string{"0.0.4"}},
+		{constraint: "^0.3", match: []string{"0.3.0", "0.3.9"}, reject: []string{"0.4.0"}},
+		{constraint: "^0", match: []string{"0.0.1", "0.9.0"}, reject: []string{"1.0.0"}},
+	}
+	for _, tt := range tests {
+		c, err := parseVersionConstraint(tt.constraint)
+		if err != nil {
+			t.Errorf("%s: %v", tt.constraint, err)
+			continue
+		}
+		for _, v := range tt.match {
+			if !c.check(mustSemver(t, v)) {
+				t.Errorf("%s should match %s", tt.constraint, v)
+			}
+		}
+		for _, v := range tt.reject {
+			if c.check(mustSemver(t, v)) {
+				t.Errorf("%s should not match %s", tt.constraint, v)
+			}
+		}
+	}
+}
+
+func TestParseVersionConstraintErrors(t *testing.T) {
+	for _, c := range []string{"=>4.6", "!4.6", ">=", "4.6.1.2", "4.6-", "four"} {
+		if _, err := parseVersionConstraint(c); err == nil {
+			t.Errorf("%q should not parse", c)
+		}
+	}
+}
+
+func TestPrereleaseOrdering(t *testing.T) {
+	// in ascending order, as in the semver specification
+	versions := []string{"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta", "1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.0.0+build"}
+	for i := 0; i+1 < len(versions); i++ {
+		a, b := mustSemver(t, versions[i]), mustSemver(t, versions[i+1])
+		if c := a.compare(b); c > 0 || (c == 0 && versions[i+1] != "1.0.0+build") {
+			t.Errorf("%s should sort before %s", versions[i], versions[i+1])
+		}
+	}
+}
+
+func TestBtcppIncompatibility(t *testing.T) {
+	tests := []struct {
+		payload, executor string
+		compatible        bool
+	}{
+		{payload: "4.6.2", executor: "4.6.2", compatible: true},
+		{payload: "4.6", executor: "4.6.2", compatible: true},
+		{payload: "4.6", executor: "4.7.0", compatible: false},
+		{payload: ">=4.6 <5", executor: "4.9.1", compatible: true},
+		{payload: ">= 4.6, < 5", executor: "4.9.1", compatible: true},
+		{payload: ">= 4.6, < 5", executor: "5.0.0", compatible: false},
+		{payload: "^3.8 || ^4.2", executor: "3.8.6", compatible: true},
+		{payload: "^3.8 || ^4.2", executor: "4.1.0", compatible: false},
+		{payload: "~ 4.6", executor: "4.6.5", compatible: true},
+		{payload: ">=", executor: "4.6.0", compatible: false},
+		{payload: "4.6 ||", executor: "4.6.0", compatible: false},
+		{payload: ">=4.6", executor: "4.6", compatible: false}, // executors must give an exact version
+		{payload: "humble", executor: "humble", compatible: true},
+		{payload: "humble", executor: "4.6.0", compatible: false},
+		{payload: "", executor: "4.6.0", compatible: false},
+	}
+	for _, tt := range tests {
+		reason := btcppIncompatibility(tt.payload, tt.executor)
+		if (reason == "") != tt.compatible {
+			t.Errorf("payload %q, executor %q: got reason %q, want compatible=%v", tt.payload, tt.executor, reason, tt.compatible)
+		}
+	}
+}
+
+func mustSemver(t *testing.T, v string) semver {
+	t.Helper()
+	s, _, err := parseSemver(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}